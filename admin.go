@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pastjean/scrumpolice/scrum"
+	"github.com/sirupsen/logrus"
+)
+
+// adminAPI serves the administration endpoints, every request needs the admin token as a bearer token
+type adminAPI struct {
+	token  string
+	scrum  scrum.Service
+	logger *logrus.Logger
+}
+
+func (api *adminAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", api.stats)
+	return api.authenticated(mux)
+}
+
+func (api *adminAPI) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expected := []byte("Bearer " + api.token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// stats returns the participation of a team: GET /stats?team=T&days=30
+func (api *adminAPI) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	stats, err := api.scrum.GetStats(r.URL.Query().Get("team"), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		api.logger.WithField("error", err).Warn("Cannot write the stats response.")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pastjean/scrumpolice/scrum"
+	"github.com/sirupsen/logrus"
+)
+
+// statsService only knows the stats of team L337
+type statsService struct {
+	scrum.Service
+	since time.Time
+}
+
+func (s *statsService) GetStats(team string, since time.Time) (*scrum.TeamStats, error) {
+	if team != "L337" {
+		return nil, errors.New("Team " + team + " does not exist")
+	}
+	s.since = since
+	return &scrum.TeamStats{Team: team, Since: since, Members: []scrum.MemberStats{{User: "U1", Streak: 3}}}, nil
+}
+
+func getStats(t *testing.T, server *httptest.Server, token string, query string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/stats?"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestAdminAPINeedsTheToken(t *testing.T) {
+	api := &adminAPI{token: "s3cr3t", scrum: &statsService{}, logger: logrus.New()}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	for _, token := range []string{"", "wrong", "s3cr3t2"} {
+		if resp := getStats(t, server, token, "team=L337"); resp.StatusCode != http.StatusUnauthorized {
+			t.Error("unexpected status", resp.Status, "for token", token)
+		}
+	}
+}
+
+func TestAdminAPIStats(t *testing.T) {
+	service := &statsService{}
+	api := &adminAPI{token: "s3cr3t", scrum: service, logger: logrus.New()}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	for _, days := range []string{"zero", "0", "-3"} {
+		if resp := getStats(t, server, "s3cr3t", "team=L337&days="+days); resp.StatusCode != http.StatusBadRequest {
+			t.Error("unexpected status", resp.Status, "for days", days)
+		}
+	}
+
+	if resp := getStats(t, server, "s3cr3t", "team=Other"); resp.StatusCode != http.StatusNotFound {
+		t.Error("unexpected status for an unknown team", resp.Status)
+	}
+
+	resp := getStats(t, server, "s3cr3t", "team=L337&days=7")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status", resp.Status)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	stats := map[string]interface{}{}
+	if err := json.Unmarshal(body, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats["streak"]; ok || stats["team"] != "L337" {
+		t.Error("unexpected team stats", string(body))
+	}
+	if members := stats["members"].([]interface{}); len(members) != 1 || members[0].(map[string]interface{})["streak"] != 3.0 {
+		t.Error("unexpected member stats", string(body))
+	}
+	if days := time.Since(service.since).Hours() / 24; days < 6.9 || days > 7.1 {
+		t.Error("unexpected stats period", service.since)
+	}
+}
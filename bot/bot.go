@@ -1,9 +1,12 @@
 package bot
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -13,6 +16,11 @@ import (
 
 var (
 	OutOfOfficeRegex, _ = regexp.Compile("^(.+) is out of office$")
+	ResolveRegex, _     = regexp.Compile("^resolve #?([0-9]+)$")
+	ForceReportRegex, _ = regexp.Compile("^force report (.+)$")
+	PauseRegex, _       = regexp.Compile("^(pause|resume) (.+)$")
+	SnoozeRegex, _      = regexp.Compile("^snooze (.+)$")
+	SearchRegex, _      = regexp.Compile("^search (.+)$")
 )
 
 type (
@@ -23,7 +31,11 @@ type (
 		userContextsMutex sync.Mutex
 		userContexts      map[string]BotContextHandler
 
+		snoozesMutex sync.Mutex
+		snoozes      map[string]*time.Timer
+
 		scrum scrum.Service
+		users *UserDirectory
 
 		name    string
 		iconURL string
@@ -33,18 +45,23 @@ type (
 	}
 )
 
-func New(slackApiClient *slack.Client, logger *log.Logger, scrum scrum.Service) *Bot {
+func New(slackApiClient *slack.Client, logger *log.Logger, scrum scrum.Service, users *UserDirectory) *Bot {
 	slackBotRTM := slackApiClient.NewRTM()
 	go slackBotRTM.ManageConnection()
 
-	return &Bot{
+	b := &Bot{
 		slackBotAPI:  slackApiClient,
 		slackBotRTM:  slackBotRTM,
 		logger:       logger,
 		userContexts: map[string]BotContextHandler{},
+		snoozes:      map[string]*time.Timer{},
 		iconURL:      "http://i.imgur.com/dzZvzXm.jpg",
 		scrum:        scrum,
+		users:        users,
 	}
+	scrum.OnOpenReport(b.openReport)
+
+	return b
 }
 
 func (b *Bot) Run() {
@@ -57,6 +74,11 @@ func (b *Bot) Run() {
 				go b.handleInvalidAuth(evt)
 			case *slack.ConnectedEvent:
 				go b.handleConnected(evt)
+				go b.reloadUsers(func() { b.users.connected(evt.Info) })
+			case *slack.UserChangeEvent:
+				go b.users.setUser(evt.User)
+			case *slack.TeamJoinEvent:
+				go b.reloadUsers(func() { b.users.setUser(evt.User) })
 			}
 		}
 	}()
@@ -64,6 +86,13 @@ func (b *Bot) Run() {
 	select {}
 }
 
+// reloadUsers updates the user directory, then resolves the configured members again
+// since some may have been unknown until now
+func (b *Bot) reloadUsers(update func()) {
+	update()
+	b.scrum.ReloadUsers()
+}
+
 func (b *Bot) handleMessage(event *slack.MessageEvent) {
 	if event.BotID != "" {
 		// Ignore the messages coming from other bots
@@ -139,6 +168,51 @@ func (b *Bot) handleMessage(event *slack.MessageEvent) {
 		return
 	}
 
+	if ResolveRegex.MatchString(eventText) {
+		b.resolveBlocker(event, ResolveRegex.FindStringSubmatch(eventText)[1])
+		return
+	}
+
+	if ForceReportRegex.MatchString(eventText) {
+		b.forceReport(event, ForceReportRegex.FindStringSubmatch(eventText)[1])
+		return
+	}
+
+	if SnoozeRegex.MatchString(eventText) {
+		b.snooze(event, SnoozeRegex.FindStringSubmatch(eventText)[1])
+		return
+	}
+
+	if eventText == "history" || strings.HasPrefix(eventText, "history ") {
+		if b.onlyInDirectMessage(event, isIM) {
+			b.history(event, strings.Fields(eventText)[1:])
+		}
+		return
+	}
+
+	if eventText == "mood" || strings.HasPrefix(eventText, "mood ") {
+		b.mood(event, strings.Fields(eventText)[1:])
+		return
+	}
+
+	if eventText == "stats" || strings.HasPrefix(eventText, "stats ") {
+		b.stats(event, strings.Fields(eventText)[1:])
+		return
+	}
+
+	if SearchRegex.MatchString(eventText) {
+		if b.onlyInDirectMessage(event, isIM) {
+			b.search(event, SearchRegex.FindStringSubmatch(eventText)[1])
+		}
+		return
+	}
+
+	if PauseRegex.MatchString(eventText) {
+		matches := PauseRegex.FindStringSubmatch(eventText)
+		b.pauseTeam(event, matches[2], matches[1] == "pause")
+		return
+	}
+
 	// Unrecognized message so let's help the user
 	b.unrecognizedMessage(event)
 	return
@@ -195,11 +269,20 @@ func (b *Bot) help(event *slack.MessageEvent) {
 		Text: "- `source code`: location of my source code\n" +
 			"- `help`: well, this command\n" +
 			"- `tutorial`: explains how the scrum police works. Try it!\n" +
-			"- `start`: starts a scrum for a team and a specific set of questions, defaults to your only team if you got only one, and only questions set if there's only one on the team you chose\n" +
+			"- `start [team] [questions] [date]`: starts a scrum for a team and a specific set of questions, defaults to your only team if you got only one, and only questions set if there's only one on the team you chose. The date (`yesterday`, `tomorrow` or YYYY-MM-DD) lets you fill a late report or one in advance\n" +
 			"- `restart`: restart your last done scrum, if it wasn't posted\n" +
+			"- `start for [user]` or `skip for [user]`: report on behalf of a member of a team you lead\n" +
 			"- `out of office`: mark current user as out of office (until `i'm back` is used)\n" +
-			"- `[user] is out of office`: mark the specified user as out of office (until he or she uses `i'm back`)\n" +
-			"- `i am back` or `i'm back`: mark current user as in office. MacOS smart quote can screw up with the `i'm back` command.",
+			"- `[user] is out of office`: mark the specified user of a team you lead as out of office (until they use `i'm back`)\n" +
+			"- `i am back` or `i'm back`: mark current user as in office. MacOS smart quote can screw up with the `i'm back` command.\n" +
+			"- `snooze [duration]`: remind me again in a while (e.g. `snooze 30m`), before the report deadline\n" +
+			"- `history [team] [user] [last N]`: shows the last reports of your teams, in a direct message\n" +
+			"- `search [text]`: finds the reports of your teams mentioning a text, in a direct message\n" +
+			"- `stats [team] [days]`: shows who reports and when in your team, for the last 30 days by default\n" +
+			"- `mood [team]`: shows the weekly trend of the mood of your teams\n" +
+			"- `resolve [id]`: mark one of your blockers as resolved, it won't show up in the reports anymore\n" +
+			"- `force report [team]`: post the reports of a team you administer right away\n" +
+			"- `pause [team]` or `resume [team]`: stop or restart the reminders and reports of a team you administer",
 	}
 
 	params := slack.PostMessageParameters{AsUser: true}
@@ -227,66 +310,143 @@ func (b *Bot) tutorial(event *slack.MessageEvent) {
 		params)
 }
 
-func (b *Bot) outOfOffice(event *slack.MessageEvent, userId string) {
+func (b *Bot) outOfOffice(event *slack.MessageEvent, user string) {
 	params := slack.PostMessageParameters{AsUser: true}
-	username := strings.TrimLeft(userId, "@")
 
-	user, err := b.slackBotAPI.GetUserInfo(username)
-	if err == nil {
-		username = user.Profile.DisplayName
+	userID, ok := b.users.UserID(user)
+	if !ok {
+		b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find any user matching '"+user+"'. Try again!", params)
+		return
 	}
 
-	teams := b.scrum.GetTeamsForUser(username)
+	teams := b.scrum.GetTeamsForUser(userID)
 	if len(teams) == 0 {
-		b.logSlackRelatedError(event, err, "Fail to get user information.")
-		b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find any user matching '"+username+"' in any team. Try again!", params)
+		b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find <@"+userID+"> in any team. Try again!", params)
 		return
 	}
 
+	if event.User != userID {
+		teams = b.teamsWithRole(teams, event.User, scrum.LeadRole)
+		if len(teams) == 0 {
+			b.slackBotAPI.PostMessage(event.Channel, "Only a lead of one of <@"+userID+">'s teams can mark them out of office", params)
+			return
+		}
+	}
+
 	for _, team := range teams {
-		b.scrum.AddToOutOfOffice(team, username)
+		b.scrum.AddToOutOfOffice(team, userID)
 	}
-	if event.User == userId {
+	if event.User == userID {
 		b.slackBotAPI.PostMessage(event.Channel, "I've marked you out of office in all your teams", params)
 		log.WithFields(log.Fields{
-			"user":   username,
-			"doneBy": username,
+			"user":   userID,
+			"doneBy": userID,
 		}).Info("User was marked out of office.")
 	} else {
-		b.slackBotAPI.PostMessage(event.Channel, "I've marked @"+username+" out of office in all of his teams", params)
-
-		user, err := b.slackBotAPI.GetUserInfo(event.User)
-		if err != nil {
-			b.logSlackRelatedError(event, err, "Fail to get user information.")
-			return
-		}
-		b.slackBotAPI.PostMessage("@"+username, "You've been marked out of office by @"+user.Name+".", params)
+		b.slackBotAPI.PostMessage(event.Channel, "I've marked <@"+userID+"> out of office in "+strings.Join(teams, ", "), params)
+		b.slackBotAPI.PostMessage(userID, "You've been marked out of office by <@"+event.User+">.", params)
 		log.WithFields(log.Fields{
-			"user":   userId,
-			"doneBy": user.Name,
+			"user":   userID,
+			"doneBy": event.User,
 		}).Info("User was marked out of office.")
 	}
 }
 
 func (b *Bot) backInOffice(event *slack.MessageEvent) {
 	params := slack.PostMessageParameters{AsUser: true}
-	user, err := b.slackBotAPI.GetUserInfo(event.User)
+	teams := b.scrum.GetTeamsForUser(event.User)
+
+	for _, team := range teams {
+		b.scrum.RemoveFromOutOfOffice(team, event.User)
+	}
+	b.slackBotAPI.PostMessage(event.Channel, "I've marked you in office in all your teams. Welcome back!", params)
+	log.WithFields(log.Fields{
+		"user": event.User,
+	}).Info("User was marked in office.")
+}
+
+func (b *Bot) resolveBlocker(event *slack.MessageEvent, blockerID string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	id, _ := strconv.Atoi(blockerID)
+	blocker, err := b.scrum.ResolveBlocker(event.User, id)
 	if err != nil {
-		b.logSlackRelatedError(event, err, "Fail to get user information.")
-		b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find you. Try again!", params)
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
 		return
 	}
-	username := user.Name
 
-	teams := b.scrum.GetTeamsForUser(username)
+	b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("Blocker #%d is resolved, good job! :tada:", blocker.ID), params)
+}
 
-	for _, team := range teams {
-		b.scrum.RemoveFromOutOfOffice(team, username)
+func (b *Bot) forceReport(event *slack.MessageEvent, teamName string) {
+	params := slack.PostMessageParameters{AsUser: true}
+	team, ok := b.teamWithRole(event, teamName, scrum.AdminRole)
+	if !ok {
+		return
 	}
-	b.slackBotAPI.PostMessage(event.Channel, "I've marked you in office in all your teams. Welcome back!", params)
+
+	if err := b.scrum.ForceReport(team); err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
+		return
+	}
+	b.slackBotAPI.PostMessage(event.Channel, "The reports of team "+team+" were posted", params)
 	log.WithFields(log.Fields{
-		"user": username,
-	}).Info("User was marked in office.")
+		"team":   team,
+		"doneBy": event.User,
+	}).Info("Team report was forced.")
+}
+
+func (b *Bot) pauseTeam(event *slack.MessageEvent, teamName string, paused bool) {
+	params := slack.PostMessageParameters{AsUser: true}
+	team, ok := b.teamWithRole(event, teamName, scrum.AdminRole)
+	if !ok {
+		return
+	}
+
+	if err := b.scrum.SetPaused(team, paused); err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
+		return
+	}
+	if paused {
+		b.slackBotAPI.PostMessage(event.Channel, "Team "+team+" is paused, no reminders nor reports until you tell me `resume "+team+"`", params)
+	} else {
+		b.slackBotAPI.PostMessage(event.Channel, "Team "+team+" is back on track", params)
+	}
+}
+
+// onlyInDirectMessage tells the user to ask again in a direct message when the command is sent in a channel
+func (b *Bot) onlyInDirectMessage(event *slack.MessageEvent, isIM bool) bool {
+	if !isIM {
+		b.slackBotAPI.PostMessage(event.Channel, "Ask me that in a direct message :flag-dm:", slack.PostMessageParameters{AsUser: true})
+	}
+	return isIM
+}
+
+// teamWithRole finds a team the user has at least the given role in, and tells them when it's not the case
+func (b *Bot) teamWithRole(event *slack.MessageEvent, teamName string, role scrum.Role) (string, bool) {
+	params := slack.PostMessageParameters{AsUser: true}
+	ts, err := b.scrum.GetTeamByName(teamName)
+	if err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
+		return "", false
+	}
+
+	if b.scrum.GetRole(ts.Name, event.User) < role {
+		b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("Only a %s of team %s can do that", role, ts.Name), params)
+		return "", false
+	}
+	return ts.Name, true
+}
+
+// teamsWithRole filters the teams the user has at least the given role in
+func (b *Bot) teamsWithRole(teams []string, userID string, role scrum.Role) []string {
+	allowed := []string{}
+	for _, team := range teams {
+		if b.scrum.GetRole(team, userID) >= role {
+			allowed = append(allowed, team)
+		}
+	}
+	return allowed
 }
 
 func (b *Bot) unrecognizedMessage(event *slack.MessageEvent) {
@@ -325,6 +485,13 @@ func (b *Bot) setUserContext(user string, context BotContextHandler) {
 	b.userContextsMutex.Unlock()
 }
 
+func (b *Bot) hasUserContext(user string) bool {
+	b.userContextsMutex.Lock()
+	defer b.userContextsMutex.Unlock()
+	_, ok := b.userContexts[user]
+	return ok
+}
+
 func (b *Bot) unsetUserContext(user string) {
 	b.userContextsMutex.Lock()
 	delete(b.userContexts, user)
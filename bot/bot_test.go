@@ -1,7 +1,15 @@
 package bot
 
-import "testing"
-import "github.com/nlopes/slack"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/pastjean/scrumpolice/scrum"
+	"github.com/sirupsen/logrus"
+)
 
 func TestHandleMessageIgnoreBotMessages(t *testing.T) {
 	bot := Bot{}
@@ -89,3 +97,158 @@ func TestHandleConnectedSetsBotNameAndId(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestUserDirectoryResolvesMentionsNamesAndPreviousNames(t *testing.T) {
+	users := &UserDirectory{users: map[string]slack.User{}, ids: map[string]string{}, inactive: map[string]bool{}}
+	if id, ok := users.UserID("<@U0JO>"); !ok || id != "U0JO" {
+		t.Error("user ids are users before the users are loaded")
+	}
+	users.setUser(slack.User{ID: "U0PA", Name: "pa", Profile: slack.UserProfile{DisplayName: "Pierre-Alexandre"}})
+	users.setUser(slack.User{ID: "U0BOT", Name: "scrumpolice", IsBot: true})
+
+	for _, user := range []string{"U0PA", "u0pa", "<@U0PA>", "<@U0PA|pa>", "@pa", "PA", "pierre-alexandre"} {
+		if id, ok := users.UserID(user); !ok || id != "U0PA" {
+			t.Error("could not resolve", user)
+		}
+	}
+	if _, ok := users.UserID("scrumpolice"); ok {
+		t.Error("bots are not users")
+	}
+	if _, ok := users.UserID("U0BOT"); ok {
+		t.Error("bot ids are not users")
+	}
+	if _, ok := users.UserID("jo"); ok {
+		t.Error("unknown names are not users")
+	}
+
+	users.setUser(slack.User{ID: "U0PA", Name: "pastjean"})
+	if id, ok := users.UserID("pa"); !ok || id != "U0PA" {
+		t.Error("previous name should still resolve")
+	}
+	if users.UserName("U0PA") != "pastjean" {
+		t.Fail()
+	}
+}
+
+func TestMatchTeamWithSpacesInTheName(t *testing.T) {
+	team, rest := matchTeam([]string{"L337", "L337 team"}, []string{"l337", "TEAM", "daily"})
+	if team != "L337 team" || len(rest) != 1 || rest[0] != "daily" {
+		t.Error("unexpected match", team, rest)
+	}
+
+	team, _ = matchTeam([]string{"L337"}, []string{"other"})
+	if team != "" {
+		t.Fail()
+	}
+}
+
+func TestParseDateOfReport(t *testing.T) {
+	now := time.Date(2018, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	for arg, expected := range map[string]string{"yesterday": "2018-03-04", "Today": "2018-03-05", "tomorrow": "2018-03-06", "2018-02-28": "2018-02-28"} {
+		date, ok := parseDate(arg, now)
+		if !ok || date.Format(scrum.DateFormat) != expected {
+			t.Error("unexpected date", date, "for", arg)
+		}
+	}
+
+	if _, ok := parseDate("daily", now); ok {
+		t.Fail()
+	}
+}
+
+func TestSnoozeIsClampedBeforeTheDeadline(t *testing.T) {
+	now := time.Date(2018, 3, 5, 8, 0, 0, 0, time.UTC)
+	deadline := time.Date(2018, 3, 5, 9, 0, 0, 0, time.UTC)
+
+	at, ok := snoozeTime(now, 30*time.Minute, deadline)
+	if !ok || !at.Equal(now.Add(30*time.Minute)) {
+		t.Error("unexpected snooze", at)
+	}
+
+	at, ok = snoozeTime(now, 2*time.Hour, deadline)
+	if !ok || !at.Equal(deadline.Add(-snoozeMargin)) {
+		t.Error("snooze not clamped before the deadline", at)
+	}
+
+	if _, ok := snoozeTime(deadline.Add(-time.Minute), time.Hour, deadline); ok {
+		t.Error("snoozed past the deadline")
+	}
+}
+
+func TestHistoryArguments(t *testing.T) {
+	filter, user, err := historyFilter([]string{"L337", "Ops"}, []string{"ops", "pa", "last", "3"})
+	if err != nil || len(filter.Teams) != 1 || filter.Teams[0] != "Ops" || user != "pa" || filter.Limit != 3 {
+		t.Error("unexpected history filter", filter, user, err)
+	}
+
+	filter, user, err = historyFilter([]string{"L337", "Ops"}, []string{})
+	if err != nil || len(filter.Teams) != 2 || user != "" || filter.Limit != defaultHistoryLength {
+		t.Error("unexpected default history filter", filter, user, err)
+	}
+
+	if _, _, err := historyFilter([]string{"L337"}, []string{"last", "few"}); err == nil {
+		t.Fail()
+	}
+}
+
+// reportService records the reports saved through the bot
+type reportService struct {
+	scrum.Service
+	saved []*scrum.Report
+}
+
+func (s *reportService) GetReportWindow(team string, qs *scrum.QuestionSet, date time.Time) (scrum.Window, error) {
+	return scrum.Window{}, nil
+}
+
+func (s *reportService) SaveReport(report *scrum.Report, qs *scrum.QuestionSet) error {
+	s.saved = append(s.saved, report)
+	return nil
+}
+
+func (s *reportService) GetCarriedAnswer(report *scrum.Report, qs *scrum.QuestionSet, question scrum.Question) (string, bool) {
+	return "", false
+}
+
+func TestOpenedReportLetsTheReminderCommandsThrough(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "channel": {"id": "D1"}, "ts": "1.1"}`))
+	}))
+	defer server.Close()
+	api := slack.SLACK_API
+	slack.SLACK_API = server.URL + "/"
+	defer func() { slack.SLACK_API = api }()
+
+	service := &reportService{}
+	b := &Bot{
+		slackBotAPI:  slack.New("xoxb"),
+		logger:       logrus.New(),
+		userContexts: map[string]BotContextHandler{},
+		snoozes:      map[string]*time.Timer{},
+		scrum:        service,
+		users:        &UserDirectory{users: map[string]slack.User{}, ids: map[string]string{}, inactive: map[string]bool{}},
+	}
+	qs := &scrum.QuestionSet{Questions: []scrum.Question{{ID: "today", Text: "What will you do today?"}}}
+	message := func(text string) *slack.MessageEvent {
+		return &slack.MessageEvent{Msg: slack.Msg{Channel: "D1", User: "U1", Text: text}}
+	}
+
+	b.openReport("U1", "L337", qs)
+	if !b.HandleScrumMessage(message("snooze 30m")) || b.hasUserContext("U1") || len(service.saved) != 0 {
+		t.Error("snooze is not left to the commands")
+	}
+
+	b.openReport("U1", "L337", qs)
+	b.HandleScrumMessage(message("skip"))
+	if len(service.saved) != 1 || !service.saved[0].Skipped || b.hasUserContext("U1") {
+		t.Error("skip did not skip the report", service.saved)
+	}
+
+	b.openReport("U1", "L337", qs)
+	b.HandleScrumMessage(message("Deploying"))
+	if len(service.saved) != 2 || service.saved[1].Answers["today"] != "Deploying" {
+		t.Error("the answer was not saved", service.saved)
+	}
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nlopes/slack"
+	"github.com/pastjean/scrumpolice/scrum"
+)
+
+const (
+	defaultHistoryLength = 5
+	maxHistoryLength     = 20
+	maxSearchResults     = 10
+)
+
+// history shows the last reports of the teams of the user: `history [team] [user] [last N]`
+func (b *Bot) history(event *slack.MessageEvent, args []string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	teams := b.scrum.GetTeamsForUser(event.User)
+	if len(teams) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, there's no history to show", params)
+		return
+	}
+
+	filter, user, err := historyFilter(teams, args)
+	if err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
+		return
+	}
+	if user != "" {
+		userID, ok := b.users.UserID(user)
+		if !ok {
+			b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find any user matching '"+user+"'. Try again!", params)
+			return
+		}
+		filter.User = userID
+	}
+
+	b.postReports(event, filter, "Here are the last reports", "I don't have any report matching that")
+}
+
+// search finds the reports of the teams of the user with answers containing the text
+func (b *Bot) search(event *slack.MessageEvent, text string) {
+	teams := b.scrum.GetTeamsForUser(event.User)
+	if len(teams) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, there's nothing to search", slack.PostMessageParameters{AsUser: true})
+		return
+	}
+
+	filter := scrum.ReportFilter{Teams: teams, Text: strings.TrimSpace(text), Limit: maxSearchResults}
+	b.postReports(event, filter, "Here are the last reports mentioning _"+filter.Text+"_", "Nobody mentioned _"+filter.Text+"_ in a report")
+}
+
+func (b *Bot) postReports(event *slack.MessageEvent, filter scrum.ReportFilter, found string, notFound string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	reports, err := b.scrum.GetReports(filter)
+	if err != nil {
+		b.logSlackRelatedError(event, err, "Fail to list reports.")
+		b.slackBotAPI.PostMessage(event.Channel, "I couldn't read the reports, try again later", params)
+		return
+	}
+	if len(reports) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, notFound, params)
+		return
+	}
+
+	for _, report := range reports {
+		params.Attachments = append(params.Attachments, b.reportAttachment(report))
+	}
+	_, _, err = b.slackBotAPI.PostMessage(event.Channel, found, params)
+	if err != nil {
+		b.logSlackRelatedError(event, err, "Fail to post message to slack.")
+	}
+}
+
+// historyFilter reads the `[team] [user] [last N]` arguments of the history command
+func historyFilter(teams []string, args []string) (scrum.ReportFilter, string, error) {
+	filter := scrum.ReportFilter{Teams: teams, Limit: defaultHistoryLength}
+
+	if len(args) >= 2 && args[len(args)-2] == "last" {
+		n, err := strconv.Atoi(args[len(args)-1])
+		if err != nil || n <= 0 {
+			return filter, "", fmt.Errorf("I can only show the last N reports, for a number N")
+		}
+		if n > maxHistoryLength {
+			n = maxHistoryLength
+		}
+		filter.Limit, args = n, args[:len(args)-2]
+	}
+
+	if team, rest := matchTeam(teams, args); team != "" {
+		filter.Teams, args = []string{team}, rest
+	}
+	return filter, strings.Join(args, " "), nil
+}
+
+func (b *Bot) reportAttachment(report *scrum.Report) slack.Attachment {
+	pretext := fmt.Sprintf("*%s* @%s in %s", report.Date.Format(scrum.DateFormat), b.users.UserName(report.User), report.Team)
+	if report.QuestionSet != "" {
+		pretext += " (" + report.QuestionSet + ")"
+	}
+
+	text := "Has nothing to declare."
+	if report.OutOfOffice {
+		text = "Was out of office."
+	} else if !report.Skipped {
+		answers := []string{}
+		for _, question := range report.Questions {
+			if answer, ok := report.Answers[question.ID]; ok {
+				answers = append(answers, "*"+question.Text+"*\n"+question.FormatAnswer(answer))
+			}
+		}
+		text = strings.Join(answers, "\n")
+	}
+
+	return slack.Attachment{
+		MarkdownIn: []string{"text", "pretext"},
+		Pretext:    pretext,
+		Text:       text,
+	}
+}
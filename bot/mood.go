@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlopes/slack"
+	"github.com/pastjean/scrumpolice/scrum"
+)
+
+const moodTrendWeeks = 8
+
+// mood shows the weekly trend of the mood answers of the teams of the user: `mood [team]`
+func (b *Bot) mood(event *slack.MessageEvent, args []string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	teams := b.scrum.GetTeamsForUser(event.User)
+	if len(args) > 0 {
+		team, _ := matchTeam(teams, args)
+		if team == "" {
+			b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("You're not part of team %s", strings.Join(args, " ")), params)
+			return
+		}
+		teams = []string{team}
+	}
+	if len(teams) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, there's no mood to show", params)
+		return
+	}
+
+	lines := []string{}
+	for _, team := range teams {
+		trend, err := b.scrum.GetMoodTrend(team, moodTrendWeeks)
+		if err != nil {
+			lines = append(lines, err.Error())
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("*%s* `%s` %s", team, scrum.Sparkline(trend), lastMood(trend)))
+	}
+
+	b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("Mood of the last %d weeks:\n%s", moodTrendWeeks, strings.Join(lines, "\n")), params)
+}
+
+// lastMood is the average of the last week with mood answers
+func lastMood(trend []scrum.MoodWeek) string {
+	for i := len(trend) - 1; i >= 0; i-- {
+		if trend[i].Answers > 0 {
+			return fmt.Sprintf("%.1f/5 the week of %s", trend[i].Average, trend[i].Start.Format(scrum.DateFormat))
+		}
+	}
+	return "nobody answered a mood question"
+}
@@ -2,22 +2,29 @@ package bot
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nlopes/slack"
 	"github.com/pastjean/scrumpolice/scrum"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	ReportForRegex, _ = regexp.Compile("^(start|skip) for (.+)$")
+)
+
 // HandleMessage handle a received message for scrums and returns if the bot shall continue to process the message or stop
 // continue = true
 // stop = false
 func (b *Bot) HandleScrumMessage(event *slack.MessageEvent) bool {
-	// "start [team] [date]"
+	// "start [team] [question set] [date]"
 	// [team == first and only team]
-	// [date == yesterday]
+	// [question set == first and only question set]
+	// [date == next report]
 	// starting scrum for team [team] date [date]. if you want to abort say quit
 
 	// this module only takes case in private messages
@@ -29,6 +36,11 @@ func (b *Bot) HandleScrumMessage(event *slack.MessageEvent) bool {
 		return context.HandleMessage(event)
 	}
 
+	if ReportForRegex.MatchString(strings.ToLower(event.Text)) {
+		matches := ReportForRegex.FindStringSubmatch(strings.ToLower(event.Text))
+		return b.startScrumFor(event, matches[2], matches[1] == "skip")
+	}
+
 	if strings.HasPrefix(strings.ToLower(event.Text), "start") {
 		return b.startScrum(event, false)
 	}
@@ -45,13 +57,7 @@ func (b *Bot) HandleScrumMessage(event *slack.MessageEvent) bool {
 }
 
 func (b *Bot) restartScrum(event *slack.MessageEvent) bool {
-	user, err := b.slackBotAPI.GetUserInfo(event.User)
-	if err != nil {
-		b.logSlackRelatedError(event, err, "Fail to get user information.")
-		return false
-	}
-
-	if !b.scrum.DeleteLastReport(user.Name) {
+	if !b.scrum.DeleteLastReport(event.User) {
 		b.slackBotAPI.PostMessage(event.Channel, "Nothing to restart, let's get out", slack.PostMessageParameters{AsUser: true})
 		return false
 	}
@@ -63,27 +69,196 @@ func (b *Bot) restartScrum(event *slack.MessageEvent) bool {
 func (b *Bot) startScrum(event *slack.MessageEvent, isSkipped bool) bool {
 	// can we infer team (aka does the user only have one team)
 	// b.scrum.GetTeamForUser(event.User)
-	user, err := b.slackBotAPI.GetUserInfo(event.User)
-	if err != nil {
-		b.logSlackRelatedError(event, err, "Fail to get user information.")
-		b.slackBotAPI.PostMessage(event.Channel, "There was an error starting your scrum, please try again", slack.PostMessageParameters{AsUser: true})
+	userID := event.User
+
+	teams := b.scrum.GetTeamsForUser(userID)
+	if len(teams) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, no point in doing a scrum report", slack.PostMessageParameters{AsUser: true})
 		return false
 	}
-	username := user.Name
 
-	teams := b.scrum.GetTeamsForUser(username)
+	// start [team] [question set] [date]
+	args := strings.Fields(event.Text)[1:]
+	date := ""
+	if len(args) > 0 {
+		if _, ok := parseDate(args[len(args)-1], time.Now()); ok {
+			date, args = args[len(args)-1], args[:len(args)-1]
+		}
+	}
+	if len(args) == 0 {
+		return b.startScrumInTeams(event, userID, teams, date, isSkipped)
+	}
+
+	team, args := matchTeam(teams, args)
+	if team == "" {
+		b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("You're not part of team %s, choose one of yours", strings.Join(args, " ")), slack.PostMessageParameters{AsUser: true})
+		return b.startScrumInTeams(event, userID, teams, date, isSkipped)
+	}
+	if len(args) == 0 {
+		return b.choosenTeam(event, userID, team, date, isSkipped)
+	}
+
+	questionSets := b.scrum.GetQuestionSetsForTeam(team)
+	questionSet := findQuestionSet(questionSets, strings.Join(args, " "))
+	if questionSet == nil {
+		b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("Team %s has no set of questions named %s", team, strings.Join(args, " ")), slack.PostMessageParameters{AsUser: true})
+		return b.choosenTeam(event, userID, team, date, isSkipped)
+	}
+	return b.choosenTeamAndContext(event, userID, team, questionSet, date, isSkipped)
+}
+
+// parseDate reads the date of a report from `yesterday`, `today`, `tomorrow` or a YYYY-MM-DD date
+func parseDate(arg string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(arg) {
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+
+	date, err := time.ParseInLocation(scrum.DateFormat, arg, now.Location())
+	return date, err == nil
+}
+
+// reportDate is the date of a report of the team, `today` and the other relative dates
+// are in the team timezone. No date means the next report.
+func (b *Bot) reportDate(team string, arg string) time.Time {
+	if arg == "" {
+		return time.Time{}
+	}
+
+	now := time.Now()
+	if ts, err := b.scrum.GetTeamByName(team); err == nil {
+		now = now.In(ts.Location())
+	}
+	date, _ := parseDate(arg, now)
+	return date
+}
+
+// matchTeam finds the team named by the first arguments, team names can have spaces
+func matchTeam(teams []string, args []string) (string, []string) {
+	team, rest := "", args
+	for _, t := range teams {
+		words := len(strings.Fields(t))
+		if words > len(args) || words <= len(strings.Fields(team)) {
+			continue
+		}
+		if strings.EqualFold(strings.Join(args[:words], " "), t) {
+			team, rest = t, args[words:]
+		}
+	}
+	return team, rest
+}
+
+// findQuestionSet finds a question set by name or by its number in the list
+func findQuestionSet(questionSets []*scrum.QuestionSet, name string) *scrum.QuestionSet {
+	for _, questionSet := range questionSets {
+		if questionSet.Name != "" && strings.EqualFold(questionSet.Name, name) {
+			return questionSet
+		}
+	}
+
+	i, err := strconv.Atoi(name)
+	if err != nil || i < 0 || i >= len(questionSets) {
+		return nil
+	}
+	return questionSets[i]
+}
+
+// questionSetLabel describes a question set in the choices, the questions are
+// listed when the set has no name
+func questionSetLabel(questionSet *scrum.QuestionSet) string {
+	if questionSet.Name == "" {
+		questions := make([]string, len(questionSet.Questions))
+		for i, question := range questionSet.Questions {
+			questions[i] = question.Text
+		}
+		return strings.Join(questions, " & ")
+	}
+
+	if questionSet.Description == "" {
+		return "*" + questionSet.Name + "*"
+	}
+	return "*" + questionSet.Name + "*: " + questionSet.Description
+}
+
+// startScrumFor starts a scrum on behalf of a member of a team the user leads
+func (b *Bot) startScrumFor(event *slack.MessageEvent, user string, isSkipped bool) bool {
+	userID, ok := b.users.UserID(user)
+	if !ok {
+		b.slackBotAPI.PostMessage(event.Channel, "Hmmmm, I couldn't find any user matching '"+user+"'. Try again!", slack.PostMessageParameters{AsUser: true})
+		return false
+	}
+
+	teams := b.teamsWithRole(b.scrum.GetTeamsForUser(userID), event.User, scrum.LeadRole)
 	if len(teams) == 0 {
-		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, no point in doing a scrum report", slack.PostMessageParameters{AsUser: true})
+		b.slackBotAPI.PostMessage(event.Channel, "Only a lead of one of <@"+userID+">'s teams can report for them", slack.PostMessageParameters{AsUser: true})
+		return false
+	}
+
+	return b.startScrumInTeams(event, userID, teams, "", isSkipped)
+}
+
+// openReport starts the questionnaire of a question set in the direct messages of a
+// member when a reminder asks for it, unless the member is already talking to the bot
+func (b *Bot) openReport(userID string, team string, questionSet *scrum.QuestionSet) {
+	logger := b.logger.WithFields(log.Fields{
+		"user": userID,
+		"team": team,
+	})
+
+	if b.hasUserContext(userID) {
+		logger.Info("User is busy, not opening the report.")
+		return
+	}
+
+	_, _, channelID, err := b.slackBotAPI.OpenIMChannel(userID)
+	if err != nil {
+		logger.WithField("error", err).Warn("Could not open the direct messages to start the report.")
+		return
+	}
+
+	// as if the member had typed `start [team] [question set]`
+	event := &slack.MessageEvent{Msg: slack.Msg{Channel: channelID, User: userID}}
+	b.choosenTeamAndContext(event, userID, team, questionSet, "", false)
+
+	b.userContextsMutex.Lock()
+	defer b.userContextsMutex.Unlock()
+	if context, ok := b.userContexts[userID]; ok {
+		b.userContexts[userID] = b.openedReportContext(team, questionSet, context)
 	}
+}
+
+// openedReportContext lets the member answer the reminder that opened the report with
+// `skip`, `snooze` or `out of office` rather than with the answer to the first question
+func (b *Bot) openedReportContext(team string, questionSet *scrum.QuestionSet, context BotContextHandler) BotContextHandler {
+	return BotContextHandlerFunc(func(event *slack.MessageEvent) bool {
+		text := strings.ToLower(strings.TrimSpace(event.Text))
+		switch {
+		case text == "skip":
+			b.unsetUserContext(event.User)
+			return b.choosenTeamAndContext(event, event.User, team, questionSet, "", true)
+		case text == "out of office" || SnoozeRegex.MatchString(text):
+			// left to the commands
+			b.unsetUserContext(event.User)
+			return true
+		}
+		return context.HandleMessage(event)
+	})
+}
 
+func (b *Bot) startScrumInTeams(event *slack.MessageEvent, userID string, teams []string, date string, isSkipped bool) bool {
 	if len(teams) == 1 {
-		return b.choosenTeam(event, username, teams[0], isSkipped)
+		return b.choosenTeam(event, userID, teams[0], date, isSkipped)
 	}
 
-	return b.chooseTeam(event, username, teams, isSkipped)
+	return b.chooseTeam(event, userID, teams, date, isSkipped)
 }
 
-func (b *Bot) chooseTeam(event *slack.MessageEvent, username string, teams []string, isSkipped bool) bool {
+func (b *Bot) chooseTeam(event *slack.MessageEvent, userID string, teams []string, date string, isSkipped bool) bool {
 	choices := make([]string, len(teams))
 	sort.Strings(teams)
 	for i, team := range teams {
@@ -98,17 +273,17 @@ func (b *Bot) chooseTeam(event *slack.MessageEvent, username string, teams []str
 
 		if i < 0 || i >= len(teams) || err != nil {
 			b.slackBotAPI.PostMessage(event.Channel, "Wrong choices, please try again :p or type `quit`", slack.PostMessageParameters{AsUser: true})
-			b.chooseTeam(event, username, teams, isSkipped)
+			b.chooseTeam(event, userID, teams, date, isSkipped)
 			return false
 		}
 
-		return b.choosenTeam(event, username, teams[i], isSkipped)
+		return b.choosenTeam(event, userID, teams[i], date, isSkipped)
 	}))
 
 	return false
 }
 
-func (b *Bot) choosenTeam(event *slack.MessageEvent, username string, team string, isSkipped bool) bool {
+func (b *Bot) choosenTeam(event *slack.MessageEvent, userID string, team string, date string, isSkipped bool) bool {
 	qs := b.scrum.GetQuestionSetsForTeam(team)
 
 	if len(qs) == 0 {
@@ -117,59 +292,81 @@ func (b *Bot) choosenTeam(event *slack.MessageEvent, username string, team strin
 	}
 
 	if len(qs) == 1 {
-		return b.choosenTeamAndContext(event, username, team, qs[0], isSkipped)
+		return b.choosenTeamAndContext(event, userID, team, qs[0], date, isSkipped)
 	}
 
-	return b.chooseContext(event, username, team, qs, isSkipped)
+	return b.chooseContext(event, userID, team, qs, date, isSkipped)
 	// get the questionset (if more than one)
 }
 
-func (b *Bot) chooseContext(event *slack.MessageEvent, username string, team string, questionSets []*scrum.QuestionSet, isSkipped bool) bool {
+func (b *Bot) chooseContext(event *slack.MessageEvent, userID string, team string, questionSets []*scrum.QuestionSet, date string, isSkipped bool) bool {
 	choices := make([]string, len(questionSets))
 	for i, questionSet := range questionSets {
-		choices[i] = fmt.Sprintf("%d - %s", i, strings.Join(questionSet.Questions, " & "))
+		choices[i] = fmt.Sprintf("%d - %s", i, questionSetLabel(questionSet))
 	}
 
 	msg := fmt.Sprintf("Choose your set of Questions to answer :\n%s", strings.Join(choices, "\n"))
 	b.slackBotAPI.PostMessage(event.Channel, msg, slack.PostMessageParameters{AsUser: true})
 
 	b.setUserContext(event.User, b.canQuitBotContextHandlerFunc(func(event *slack.MessageEvent) bool {
-		i, err := strconv.Atoi(event.Text)
+		questionSet := findQuestionSet(questionSets, strings.TrimSpace(event.Text))
 
-		if i < 0 || i >= len(questionSets) || err != nil {
+		if questionSet == nil {
 			b.slackBotAPI.PostMessage(event.Channel, "Wrong choices, please try again :p or type `quit`", slack.PostMessageParameters{AsUser: true})
-			b.chooseContext(event, username, team, questionSets, isSkipped)
+			b.chooseContext(event, userID, team, questionSets, date, isSkipped)
 			return false
 		}
 
-		return b.choosenTeamAndContext(event, username, team, questionSets[i], isSkipped)
+		return b.choosenTeamAndContext(event, userID, team, questionSet, date, isSkipped)
 	}))
 
 	return false
 }
 
-func (b *Bot) choosenTeamAndContext(event *slack.MessageEvent, username string, team string, questionSet *scrum.QuestionSet, isSkipped bool) bool {
-	if isSkipped {
-		msg := fmt.Sprintf("Scrum report skipped for %s in team %s, type `restart` if it should not be skipped", username, team)
-		b.slackBotAPI.PostMessage(event.Channel, msg, slack.PostMessageParameters{AsUser: true})
+func (b *Bot) choosenTeamAndContext(event *slack.MessageEvent, userID string, team string, questionSet *scrum.QuestionSet, dateArg string, isSkipped bool) bool {
+	date := b.reportDate(team, dateArg)
+	window, err := b.scrum.GetReportWindow(team, questionSet, date)
+	if err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), slack.PostMessageParameters{AsUser: true})
+		b.unsetUserContext(event.User)
+		return false
+	}
 
-		b.scrum.SaveReport(&scrum.Report{
-			User:    username,
-			Team:    team,
-			Skipped: true,
-			Answers: map[string]string{},
+	if isSkipped {
+		err := b.scrum.SaveReport(&scrum.Report{
+			User:       userID,
+			ReportedBy: reportedBy(event, userID),
+			Team:       team,
+			Date:       date,
+			Skipped:    true,
+			StartedAt:  time.Now(),
+			Answers:    map[string]string{},
 		}, questionSet)
 		b.unsetUserContext(event.User)
+		if err != nil {
+			b.slackBotAPI.PostMessage(event.Channel, err.Error(), slack.PostMessageParameters{AsUser: true})
+			return false
+		}
+
+		msg := fmt.Sprintf("Scrum report skipped for %s in team %s%s, type `restart` if it should not be skipped", b.users.UserName(userID), team, forDate(window.Date))
+		b.slackBotAPI.PostMessage(event.Channel, msg, slack.PostMessageParameters{AsUser: true})
+		b.cancelSnooze(userID)
 		return false
 	}
 
-	msg := fmt.Sprintf("Scrum report started %s for team %s, type `quit` anytime to stop", username, team)
+	msg := fmt.Sprintf("Scrum report started %s for team %s%s, type `quit` anytime to stop", b.users.UserName(userID), team, forDate(window.Date))
 	b.slackBotAPI.PostMessage(event.Channel, msg, slack.PostMessageParameters{AsUser: true})
 
 	return b.answerQuestions(event, questionSet, &scrum.Report{
-		User:    username,
-		Team:    team,
-		Answers: map[string]string{},
+		User:        userID,
+		ReportedBy:  reportedBy(event, userID),
+		Team:        team,
+		QuestionSet: questionSet.Name,
+		Date:        date,
+		Window:      window,
+		Questions:   questionSet.Questions,
+		StartedAt:   time.Now(),
+		Answers:     map[string]string{},
 	})
 }
 
@@ -177,7 +374,12 @@ func (b *Bot) answerQuestions(event *slack.MessageEvent, questionSet *scrum.Ques
 	ans := len(report.Answers)
 	// We're finished, are we ?
 	if ans == len(questionSet.Questions) {
-		b.scrum.SaveReport(report, questionSet)
+		if err := b.scrum.SaveReport(report, questionSet); err != nil {
+			b.slackBotAPI.PostMessage(event.Channel, err.Error()+", your answers were not saved", slack.PostMessageParameters{AsUser: true})
+			b.unsetUserContext(event.User)
+			return false
+		}
+		b.cancelSnooze(report.User)
 		b.slackBotAPI.PostMessage(event.Channel, "Thanks for your scrum report my :deer:! :bear: with us for the digest. :owl: see you later!\n If you want to start again just say `restart`", slack.PostMessageParameters{AsUser: true})
 		b.unsetUserContext(event.User)
 		b.logger.WithFields(log.Fields{
@@ -192,10 +394,36 @@ func (b *Bot) answerQuestions(event *slack.MessageEvent, questionSet *scrum.Ques
 
 func (b *Bot) questionsOut(event *slack.MessageEvent, questionSet *scrum.QuestionSet, report *scrum.Report) bool {
 	question := questionSet.Questions[len(report.Answers)]
-	b.slackBotAPI.PostMessage(event.Channel, question, slack.PostMessageParameters{AsUser: true})
+	text := question.Text
+	if question.IsMood() {
+		text += "\n" + scrum.MoodScale()
+	}
+	previous, carried := b.scrum.GetCarriedAnswer(report, questionSet, question)
+	if carried {
+		if report.Carried == nil {
+			report.Carried = map[string]string{}
+		}
+		report.Carried[question.ID] = previous
+		text += "\nLast time you said:\n> " + strings.Replace(previous, "\n", "\n> ", -1) + "\nType `same` to answer the same"
+	}
+	b.slackBotAPI.PostMessage(event.Channel, text, slack.PostMessageParameters{AsUser: true})
 
 	ctx := b.canQuitBotContextHandlerFunc(func(event *slack.MessageEvent) bool {
-		report.Answers[question] = event.Text
+		answer := event.Text
+		if carried && strings.EqualFold(strings.TrimSpace(answer), "same") {
+			answer = previous
+		}
+		if question.IsMood() {
+			mood, ok := scrum.ParseMood(answer)
+			if !ok {
+				b.slackBotAPI.PostMessage(event.Channel, "Answer with a number from 1 to 5 or one of the emojis, or type `quit`", slack.PostMessageParameters{AsUser: true})
+				return b.questionsOut(event, questionSet, report)
+			}
+			report.Answers[question.ID] = strconv.Itoa(mood)
+			return b.answerQuestions(event, questionSet, report)
+		}
+
+		report.Answers[question.ID] = answer
 		return b.answerQuestions(event, questionSet, report)
 	})
 
@@ -203,3 +431,18 @@ func (b *Bot) questionsOut(event *slack.MessageEvent, questionSet *scrum.Questio
 
 	return false
 }
+
+func forDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return " for the report of " + date.Format(scrum.DateFormat)
+}
+
+// reportedBy is the user entering a report on behalf of another one, if any
+func reportedBy(event *slack.MessageEvent, userID string) string {
+	if event.User == userID {
+		return ""
+	}
+	return event.User
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// snoozeMargin is how long before the report deadline a snoozed reminder is sent at the latest
+const snoozeMargin = 5 * time.Minute
+
+func (b *Bot) snooze(event *slack.MessageEvent, delay string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	d, err := time.ParseDuration(delay)
+	if err != nil || d <= 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "I don't get for how long you want to snooze, try something like `snooze 30m`", params)
+		return
+	}
+
+	pending := b.scrum.GetPendingReports(event.User)
+	if len(pending) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You have no report to fill, no need to snooze :sleeping:", params)
+		return
+	}
+
+	now := time.Now()
+	deadline := pending[0].Deadline
+	at, ok := snoozeTime(now, d, deadline)
+	if !ok {
+		b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("Your report for team %s is due at %s, that's too soon to snooze. `start` it now!", pending[0].Team, deadline.Format("15:04")), params)
+		return
+	}
+
+	b.setSnooze(event.User, at.Sub(now))
+	b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("OK, I'll remind you at %s :zzz:", at.In(deadline.Location()).Format("15:04")), params)
+	log.WithFields(log.Fields{
+		"user": event.User,
+		"at":   at,
+	}).Info("Reminder snoozed.")
+}
+
+// snoozeTime is when to remind a member who snoozed for d, a bit before the
+// deadline at the latest. It is not ok when that time is already past.
+func snoozeTime(now time.Time, d time.Duration, deadline time.Time) (time.Time, bool) {
+	at := now.Add(d)
+	if latest := deadline.Add(-snoozeMargin); at.After(latest) {
+		at = latest
+	}
+	return at, at.After(now)
+}
+
+// setSnooze replaces the snoozed reminder of a user
+func (b *Bot) setSnooze(user string, delay time.Duration) {
+	b.snoozesMutex.Lock()
+	defer b.snoozesMutex.Unlock()
+
+	if timer, ok := b.snoozes[user]; ok {
+		timer.Stop()
+	}
+	b.snoozes[user] = time.AfterFunc(delay, func() {
+		b.cancelSnooze(user)
+		b.remindSnoozed(user)
+	})
+}
+
+// cancelSnooze drops the snoozed reminder of a user, when they fill their report
+func (b *Bot) cancelSnooze(user string) {
+	b.snoozesMutex.Lock()
+	defer b.snoozesMutex.Unlock()
+
+	if timer, ok := b.snoozes[user]; ok {
+		timer.Stop()
+		delete(b.snoozes, user)
+	}
+}
+
+func (b *Bot) remindSnoozed(user string) {
+	pending := b.scrum.GetPendingReports(user)
+	if len(pending) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("Wake up! :alarm_clock: Your report for team %s is due at %s, `start` to do it or `skip` if you have nothing to say", pending[0].Team, pending[0].Deadline.Format("15:04"))
+	_, _, err := b.slackBotAPI.PostMessage(user, msg, slack.PostMessageParameters{AsUser: true})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user":  user,
+			"error": err,
+		}).Warn("Could not send snoozed reminder.")
+	}
+}
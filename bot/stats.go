@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/pastjean/scrumpolice/scrum"
+)
+
+const defaultStatsDays = 30
+
+// stats shows the participation of the members of a team: `stats [team] [days]`
+func (b *Bot) stats(event *slack.MessageEvent, args []string) {
+	params := slack.PostMessageParameters{AsUser: true}
+
+	teams := b.scrum.GetTeamsForUser(event.User)
+	if len(teams) == 0 {
+		b.slackBotAPI.PostMessage(event.Channel, "You're not part of a team, there are no stats to show", params)
+		return
+	}
+
+	days := defaultStatsDays
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[len(args)-1]); err == nil && n > 0 {
+			days, args = n, args[:len(args)-1]
+		}
+	}
+
+	team := teams[0]
+	if len(args) > 0 {
+		if team, _ = matchTeam(teams, args); team == "" {
+			b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf("You're not part of team %s", strings.Join(args, " ")), params)
+			return
+		}
+	} else if len(teams) > 1 {
+		b.slackBotAPI.PostMessage(event.Channel, "Which team? `stats [team]` with one of "+strings.Join(teams, ", "), params)
+		return
+	}
+
+	stats, err := b.scrum.GetStats(team, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		b.slackBotAPI.PostMessage(event.Channel, err.Error(), params)
+		return
+	}
+
+	lines := []string{}
+	for _, member := range stats.Members {
+		lines = append(lines, fmt.Sprintf("@%s: %s, streak of %d", b.users.UserName(member.User), statsSummary(member.Stats), member.Streak))
+	}
+	params.Attachments = []slack.Attachment{{
+		MarkdownIn: []string{"text", "pretext"},
+		Pretext:    "Team " + stats.Team + ": " + statsSummary(stats.Stats),
+		Text:       strings.Join(lines, "\n"),
+	}}
+	b.slackBotAPI.PostMessage(event.Channel, fmt.Sprintf(":bar_chart: Participation of the last %d days", days), params)
+}
+
+func statsSummary(stats scrum.Stats) string {
+	summary := fmt.Sprintf("%d/%d reports (%.0f%%), %.0f%% skipped", stats.Reported, stats.Expected, stats.CompletionRate*100, stats.SkipRate*100)
+	if stats.AverageBeforeDeadline > 0 {
+		summary += ", " + stats.AverageBeforeDeadline.Round(time.Minute).String() + " before the deadline on average"
+	} else if stats.AverageBeforeDeadline < 0 {
+		summary += ", " + (-stats.AverageBeforeDeadline).Round(time.Minute).String() + " late on average"
+	}
+	return summary
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// UserDirectory caches the slack users of the team. It is loaded when the bot
+// connects and kept up to date from the user_change and team_join events.
+type UserDirectory struct {
+	sync.RWMutex
+	slackBotAPI *slack.Client
+
+	users map[string]slack.User
+	// lower cased names, display names and previous names of the users
+	ids map[string]string
+	// deleted users and bots, their ids are not users anymore
+	inactive map[string]bool
+}
+
+// userIDRegex matches the slack user ids, they are accepted before the users are loaded
+var userIDRegex = regexp.MustCompile(`^[UW][A-Z0-9]{2,}$`)
+
+func NewUserDirectory(slackBotAPI *slack.Client) *UserDirectory {
+	d := &UserDirectory{
+		slackBotAPI: slackBotAPI,
+		users:       map[string]slack.User{},
+		ids:         map[string]string{},
+		inactive:    map[string]bool{},
+	}
+	d.load()
+	return d
+}
+
+func (d *UserDirectory) load() {
+	users, err := d.slackBotAPI.GetUsers()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Warn("Cannot list slack users.")
+		return
+	}
+
+	d.setUsers(users)
+}
+
+// connected reloads the users, rtm.start sends them along but rtm.connect doesn't
+func (d *UserDirectory) connected(info *slack.Info) {
+	if len(info.Users) == 0 {
+		d.load()
+		return
+	}
+	d.setUsers(info.Users)
+}
+
+func (d *UserDirectory) setUsers(users []slack.User) {
+	for _, user := range users {
+		d.setUser(user)
+	}
+}
+
+// setUser adds or updates a user, the previous names of the user still resolve
+// to it so the configuration doesn't need to follow renames
+func (d *UserDirectory) setUser(user slack.User) {
+	d.Lock()
+	defer d.Unlock()
+
+	if user.Deleted || user.IsBot {
+		delete(d.users, user.ID)
+		d.inactive[user.ID] = true
+		return
+	}
+	delete(d.inactive, user.ID)
+
+	if previous, ok := d.users[user.ID]; ok && previous.Name != user.Name {
+		log.WithFields(log.Fields{
+			"user":     user.ID,
+			"previous": previous.Name,
+			"name":     user.Name,
+		}).Info("User renamed.")
+	}
+
+	d.users[user.ID] = user
+	if user.Profile.DisplayName != "" {
+		if _, taken := d.ids[strings.ToLower(user.Profile.DisplayName)]; !taken {
+			d.ids[strings.ToLower(user.Profile.DisplayName)] = user.ID
+		}
+	}
+	// names are unique and win over display names
+	d.ids[strings.ToLower(user.Name)] = user.ID
+}
+
+// UserID finds the id of a user from its id, mention, name or display name. A user
+// id is its own id even when the users could not be loaded or don't know it yet.
+func (d *UserDirectory) UserID(user string) (string, bool) {
+	user = normalizeUser(user)
+
+	d.RLock()
+	defer d.RUnlock()
+
+	if _, ok := d.users[strings.ToUpper(user)]; ok {
+		return strings.ToUpper(user), true
+	}
+	if id, ok := d.ids[strings.ToLower(user)]; ok {
+		_, active := d.users[id]
+		return id, active
+	}
+	if userIDRegex.MatchString(user) && !d.inactive[user] {
+		return user, true
+	}
+	return "", false
+}
+
+// UserName is the current name of a user
+func (d *UserDirectory) UserName(id string) string {
+	d.RLock()
+	defer d.RUnlock()
+
+	if user, ok := d.users[id]; ok {
+		return user.Name
+	}
+	return id
+}
+
+// UserLocation is the timezone set in the slack profile of a user
+func (d *UserDirectory) UserLocation(id string) *time.Location {
+	d.RLock()
+	user, ok := d.users[id]
+	d.RUnlock()
+	if !ok || user.TZ == "" {
+		return nil
+	}
+
+	location, err := time.LoadLocation(user.TZ)
+	if err != nil {
+		return nil
+	}
+	return location
+}
+
+// normalizeUser strips the mention and @ decorations around a user
+func normalizeUser(user string) string {
+	user = strings.TrimSpace(user)
+	user = strings.TrimSuffix(strings.TrimPrefix(user, "<@"), ">")
+	if i := strings.Index(user, "|"); i >= 0 {
+		user = user[:i]
+	}
+	return strings.TrimLeft(user, "@")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/pastjean/scrumpolice/bot"
+	"github.com/pastjean/scrumpolice/scrum"
+)
+
+// export writes the stored reports of a team: scrumpolice export --team T --from D --to D --format csv|json|md
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	reportsFile := flags.String("reports", "reports.json", "The file the reports are stored in")
+	team := flags.String("team", "", "The team to export, every team when empty")
+	from := flags.String("from", "", "The first date to export (YYYY-MM-DD)")
+	to := flags.String("to", "", "The last date to export (YYYY-MM-DD)")
+	format := flags.String("format", "csv", "The export format: csv, json or md")
+	output := flags.String("output", "", "The file to export to, the standard output when empty")
+	flags.Parse(args)
+
+	filter := scrum.ReportFilter{}
+	if *team != "" {
+		filter.Teams = []string{*team}
+	}
+	var err error
+	if filter.From, err = parseExportDate(*from); err != nil {
+		return err
+	}
+	if filter.To, err = parseExportDate(*to); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*reportsFile); err != nil {
+		return err
+	}
+	store, err := scrum.NewFileReportStore(*reportsFile)
+	if err != nil {
+		return err
+	}
+	reports, err := store.Reports(filter)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return scrum.ExportReports(w, *format, reports, exportUserName())
+}
+
+func parseExportDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.Parse(scrum.DateFormat, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s', use YYYY-MM-DD", date)
+	}
+	return d, nil
+}
+
+// exportUserName shows the slack user names when the slack token is set, the user ids otherwise
+func exportUserName() func(id string) string {
+	slackBotToken := os.Getenv("SCRUMPOLICE_SLACK_TOKEN")
+	if slackBotToken == "" {
+		return func(id string) string { return id }
+	}
+	return bot.NewUserDirectory(slack.New(slackBotToken)).UserName
+}
@@ -0,0 +1,145 @@
+package scrum
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Blocker is raised from a blocker question answer and stays open until its owner resolves it
+type Blocker struct {
+	ID         int
+	Team       string
+	User       string
+	QuestionID string
+	Text       string
+	RaisedAt   time.Time
+	ResolvedAt time.Time
+}
+
+func (b *Blocker) IsResolved() bool {
+	return !b.ResolvedAt.IsZero()
+}
+
+// AgeInDays is the number of full days the blocker has been open
+func (b *Blocker) AgeInDays(now time.Time) int {
+	return int(now.Sub(b.RaisedAt).Hours() / 24)
+}
+
+func (b *Blocker) age(now time.Time) string {
+	switch days := b.AgeInDays(now); days {
+	case 0:
+		return "raised today"
+	case 1:
+		return "open for 1 day"
+	default:
+		return fmt.Sprintf("open for %d days", days)
+	}
+}
+
+// blockerRegistry keeps track of the blockers, in the store when it has one so they survive a restart
+type blockerRegistry struct {
+	sync.Mutex
+	lastID   int
+	blockers map[int]*Blocker
+	store    ReportStore
+}
+
+func newBlockerRegistry(store ReportStore) *blockerRegistry {
+	r := &blockerRegistry{blockers: map[int]*Blocker{}, store: store}
+	if store == nil {
+		return r
+	}
+
+	blockers, err := store.Blockers()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("Cannot load the blockers.")
+	}
+	for _, b := range blockers {
+		r.blockers[b.ID] = b
+		if b.ID > r.lastID {
+			r.lastID = b.ID
+		}
+	}
+	return r
+}
+
+func (r *blockerRegistry) save(b *Blocker) {
+	if r.store == nil {
+		return
+	}
+	if err := r.store.SaveBlocker(b); err != nil {
+		log.WithFields(log.Fields{
+			"team":    b.Team,
+			"blocker": b.ID,
+			"error":   err,
+		}).Error("Cannot store blocker.")
+	}
+}
+
+// raise registers a blocker, the same blocker reported again by its owner is not duplicated
+func (r *blockerRegistry) raise(team, user, questionID, text string, at time.Time) (blocker *Blocker, isNew bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, b := range r.blockers {
+		if !b.IsResolved() && b.Team == team && b.User == user && b.QuestionID == questionID &&
+			strings.EqualFold(strings.TrimSpace(b.Text), strings.TrimSpace(text)) {
+			return b, false
+		}
+	}
+
+	r.lastID++
+	b := &Blocker{
+		ID:         r.lastID,
+		Team:       team,
+		User:       user,
+		QuestionID: questionID,
+		Text:       text,
+		RaisedAt:   at,
+	}
+	r.blockers[b.ID] = b
+	r.save(b)
+	return b, true
+}
+
+func (r *blockerRegistry) resolve(id int, user string, at time.Time) (*Blocker, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	b, ok := r.blockers[id]
+	if !ok {
+		return nil, fmt.Errorf("There is no blocker #%d", id)
+	}
+	if b.User != user {
+		return nil, fmt.Errorf("Blocker #%d belongs to <@%s>, only they can resolve it", id, b.User)
+	}
+	if b.IsResolved() {
+		return nil, fmt.Errorf("Blocker #%d is already resolved", id)
+	}
+
+	b.ResolvedAt = at
+	r.save(b)
+	return b, nil
+}
+
+// open lists the unresolved blockers of a team, oldest first
+func (r *blockerRegistry) open(team string) []*Blocker {
+	r.Lock()
+	defer r.Unlock()
+
+	blockers := []*Blocker{}
+	for _, b := range r.blockers {
+		if b.Team == team && !b.IsResolved() {
+			blockers = append(blockers, b)
+		}
+	}
+	sort.Slice(blockers, func(i, j int) bool { return blockers[i].ID < blockers[j].ID })
+	return blockers
+}
@@ -0,0 +1,61 @@
+package scrum
+
+import "fmt"
+
+// CompletionPolicy tells when a report is complete and posted before its scheduled time
+type CompletionPolicy string
+
+const (
+	// CompleteWhenAllReported posts the report once every member reported
+	CompleteWhenAllReported CompletionPolicy = "all"
+	// CompleteWhenInOfficeReported posts the report once every member in office reported
+	CompleteWhenInOfficeReported CompletionPolicy = "in_office"
+	// CompleteOnQuorum posts the report once a percentage of the members in office reported
+	CompleteOnQuorum CompletionPolicy = "quorum"
+	// CompleteOnSchedule only posts the report at its scheduled time
+	CompleteOnSchedule CompletionPolicy = "scheduled"
+)
+
+func newCompletionPolicy(policy string, quorum int) (CompletionPolicy, error) {
+	switch p := CompletionPolicy(policy); p {
+	case "":
+		return CompleteWhenAllReported, nil
+	case CompleteWhenAllReported, CompleteWhenInOfficeReported, CompleteOnSchedule:
+		return p, nil
+	case CompleteOnQuorum:
+		if quorum <= 0 || quorum > 100 {
+			return "", fmt.Errorf("quorum must be a percentage between 1 and 100, got %d", quorum)
+		}
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown completion policy '%s'", policy)
+	}
+}
+
+// isComplete tells if the report of the question set can be posted right away
+func (ts *TeamState) isComplete(qsstate *questionSetState) bool {
+	reported, inOffice, reportedInOffice := 0, 0, 0
+	for _, member := range ts.Members {
+		_, ok := qsstate.enteredReports[member]
+		if ok {
+			reported++
+		}
+		if !isMemberOutOfOffice(ts, member) {
+			inOffice++
+			if ok {
+				reportedInOffice++
+			}
+		}
+	}
+
+	switch qsstate.Completion {
+	case CompleteOnSchedule:
+		return false
+	case CompleteWhenInOfficeReported:
+		return reported > 0 && reportedInOffice == inOffice
+	case CompleteOnQuorum:
+		return reported > 0 && reportedInOffice*100 >= qsstate.Quorum*inOffice
+	default:
+		return reported == len(ts.Members)
+	}
+}
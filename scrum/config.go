@@ -2,8 +2,11 @@ package scrum
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -12,9 +15,12 @@ import (
 
 // {
 //   "timezone": "America/Montreal",
+//   "members_sync_interval": "15m",
+//   "admins": ["pa"],
+//   "webhooks": [{"url": "https://example.com/standups", "secret": "s3cr3t"}],
 //   "teams": [
 //     {
-//       "channel": "general",
+//       "channels": ["general"],
 //       "name": "L337",
 //       "members": [
 //         "fboutin2",
@@ -22,18 +28,41 @@ import (
 //         "pa",
 //         "jo"
 //       ],
+//       "members_from_usergroup": "l337-devs",
+//       "exclude_members": ["jo"],
 //       "split_report": false,
+//       "leads": ["pa"],
+//       "admins": ["lbourdages"],
+//       "escalation_channel": "leads",
+//       "digest": {"schedule_cron": "0 0 17 * * 5", "to": "lead"},
+//       "webhooks": [{"url": "https://example.com/l337", "events": ["report_posted"]}],
 //       "question_sets": [
 //         {
+//           "name": "daily",
+//           "description": "The daily standup",
 //           "questions": [
-//             "What did you do yesterday?",
-//             "What will you do today?",
-//             "Are you being blocked by someone for a review? who ? why ?",
-//             "How will you dominate the world"
+//             {"id": "yesterday", "text": "What did you do yesterday?", "carry_from": "today", "compare": true},
+//             {"id": "today", "text": "What will you do today?"},
+//             {"id": "blockers", "text": "Are you being blocked by someone for a review? who ? why ?", "blocker": true},
+//             "How will you dominate the world",
+//             {"id": "mood", "text": "How do you feel?", "type": "mood"}
+//           ],
+//           "destinations": [
+//             {"channel": "general"},
+//             {"channel": "leads", "questions": ["blockers"]}
 //           ],
 //           "report_schedule_cron": "@every 30s",
-//           "first_reminder_limit": "-8s",
-//           "last_reminder_limit": "-3s"
+//           "reminders": [
+//             {"offset": "-10s", "audience": "dm"},
+//             {"offset": "-8s", "audience": "dm", "template": "{{.Member}} the report is due at {{.Deadline}}"},
+//             {"offset": "-6s", "audience": "dm", "open_report": true},
+//             {"offset": "-5s", "audience": "lead"},
+//             {"offset": "-3s", "audience": "channel"}
+//           ],
+//           "late_grace_period": "10s",
+//           "report_window": {"opens": "-25s", "closes": "10s"},
+//           "completion": "quorum",
+//           "quorum": 75
 //         }
 //       ]
 //     }
@@ -47,27 +76,111 @@ type (
 
 	// Config is the configuration format
 	Config struct {
-		Timezone string       `json:"timezone"`
-		Teams    []TeamConfig `json:"teams"`
+		Timezone            string          `json:"timezone"`
+		MembersSyncInterval string          `json:"members_sync_interval"`
+		Admins              []string        `json:"admins"`
+		Webhooks            []WebhookConfig `json:"webhooks"`
+		Teams               []TeamConfig    `json:"teams"`
 	}
 
 	TeamConfig struct {
-		Name         string              `json:"name"`
-		Channel      string              `json:"channel"`
-		Members      []string            `json:"members"`
-		QuestionSets []QuestionSetConfig `json:"question_sets"`
-		Timezone     string              `json:"timezone"`
-		SplitReport  bool                `json:"split_report"`
+		Name                 string              `json:"name"`
+		Channel              string              `json:"channel"`
+		Channels             []string            `json:"channels"`
+		Members              []string            `json:"members"`
+		MembersFromUserGroup string              `json:"members_from_usergroup"`
+		MembersFromChannel   string              `json:"members_from_channel"`
+		ExcludeMembers       []string            `json:"exclude_members"`
+		QuestionSets         []QuestionSetConfig `json:"question_sets"`
+		Timezone             string              `json:"timezone"`
+		SplitReport          bool                `json:"split_report"`
+		Leads                []string            `json:"leads"`
+		Admins               []string            `json:"admins"`
+		EscalationChannel    string              `json:"escalation_channel"`
+		Digest               *DigestConfig       `json:"digest"`
+		Webhooks             []WebhookConfig     `json:"webhooks"`
+	}
+
+	// WebhookConfig receives the listed events of the reports (all of them when none
+	// is listed) as json payloads, signed with the secret when there is one
+	WebhookConfig struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+
+	// DigestConfig schedules the weekly digest of a team, posted to the team "channel"
+	// (the default) or sent to the team leads ("lead")
+	DigestConfig struct {
+		ScheduleCron string `json:"schedule_cron"`
+		To           string `json:"to"`
 	}
 
 	QuestionSetConfig struct {
-		Questions                 []string `json:"questions"`
-		ReportScheduleCron        string   `json:"report_schedule_cron"`
-		FirstReminderBeforeReport string   `json:"first_reminder_limit"`
-		LastReminderBeforeReport  string   `json:"last_reminder_limit"`
+		Name                      string              `json:"name"`
+		Description               string              `json:"description"`
+		Questions                 []QuestionConfig    `json:"questions"`
+		Destinations              []DestinationConfig `json:"destinations"`
+		ReportScheduleCron        string              `json:"report_schedule_cron"`
+		Reminders                 []ReminderConfig    `json:"reminders"`
+		FirstReminderBeforeReport string              `json:"first_reminder_limit"`
+		LastReminderBeforeReport  string              `json:"last_reminder_limit"`
+		LateGracePeriod           string              `json:"late_grace_period"`
+		ReportWindow              *ReportWindowConfig `json:"report_window"`
+		Completion                string              `json:"completion"`
+		Quorum                    int                 `json:"quorum"`
+	}
+
+	// ReminderConfig is sent at an offset from the report time (e.g. "-30m") to the
+	// members who didn't report yet: "dm" messages them, "channel" pings them in the
+	// report channels and "lead" tells the team leads. The template is a text/template.
+	// A "dm" reminder can open the report, the next message of the member answers the first question.
+	ReminderConfig struct {
+		Offset     string `json:"offset"`
+		Audience   string `json:"audience"`
+		Template   string `json:"template"`
+		OpenReport bool   `json:"open_report"`
+	}
+
+	// ReportWindowConfig opens and closes the reports at offsets from the report time
+	// (e.g. "-12h" and "1h"), the reports entered outside of it are rejected
+	ReportWindowConfig struct {
+		Opens  string `json:"opens"`
+		Closes string `json:"closes"`
+	}
+
+	// DestinationConfig is a channel receiving the report of a question set,
+	// only the answers to the listed question ids are posted when there are some
+	DestinationConfig struct {
+		Channel   string   `json:"channel"`
+		Questions []string `json:"questions"`
+	}
+
+	// QuestionConfig is either a plain string (the question text) or an object
+	// with an explicit id. Questions without an id are identified by their position.
+	QuestionConfig struct {
+		ID      string `json:"id"`
+		Text    string `json:"text"`
+		Blocker bool   `json:"blocker"`
+		Type    string `json:"type"`
+		// CarryFrom shows the previous answer to another question, e.g. the plan of the day
+		// before when asking what was done, Compare shows both answers in the report
+		CarryFrom string `json:"carry_from"`
+		Compare   bool   `json:"compare"`
 	}
 )
 
+func (qc *QuestionConfig) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*qc = QuestionConfig{Text: text}
+		return nil
+	}
+
+	type questionConfig QuestionConfig
+	return json.Unmarshal(data, (*questionConfig)(qc))
+}
+
 type configFileWatcher struct {
 	config         *Config
 	changeHandlers []func(cfg *Config)
@@ -128,6 +241,54 @@ func (fw *configFileWatcher) reloadAndDistributeChange(filename string) {
 	}
 }
 
+// membersSyncInterval is how often the members of the teams are synchronized from slack
+func (c *Config) membersSyncInterval() time.Duration {
+	if c.MembersSyncInterval == "" {
+		return 15 * time.Minute
+	}
+
+	interval, err := time.ParseDuration(c.MembersSyncInterval)
+	if err != nil || interval <= 0 {
+		log.Println("Invalid members_sync_interval '", c.MembersSyncInterval, "', will use 15m")
+		return 15 * time.Minute
+	}
+	return interval
+}
+
+// webhooks are the webhooks receiving the events of every team
+func (c *Config) webhooks() []Webhook {
+	return toWebhooks("", c.Webhooks)
+}
+
+func toWebhooks(team string, configs []WebhookConfig) []Webhook {
+	webhooks := []Webhook{}
+	for _, wc := range configs {
+		webhook, err := wc.toWebhook()
+		if err != nil {
+			log.Println("error parsing webhook for team", team, err)
+			continue
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks
+}
+
+func (wc *WebhookConfig) toWebhook() (Webhook, error) {
+	u, err := url.Parse(wc.URL)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return Webhook{}, fmt.Errorf("a webhook needs an http or https url, not '%s'", wc.URL)
+	}
+	for _, event := range wc.Events {
+		if !isWebhookEvent(event) {
+			return Webhook{}, fmt.Errorf("unknown webhook event '%s', use one of %s", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	return Webhook{URL: wc.URL, Secret: wc.Secret, Events: wc.Events}, nil
+}
+
 func (c *Config) ToTeams() []*Team {
 	teams := []*Team{}
 	for _, teamConfig := range c.Teams {
@@ -138,21 +299,52 @@ func (c *Config) ToTeams() []*Team {
 
 func (tc *TeamConfig) ToTeam() *Team {
 	qsets := []*QuestionSet{}
-	for _, questionsetconfig := range tc.QuestionSets {
+	unnamed := 0
+	for i, questionsetconfig := range tc.QuestionSets {
 		qs, err := questionsetconfig.toQuestionSet()
+		if err == nil && qs.Name != "" && hasQuestionSetNamed(qsets, qs.Name) {
+			// the reports are stored by question set name, the unnamed sets by their rank
+			err = fmt.Errorf("the question sets of a team need different names, '%s' is already used", qs.Name)
+		}
 		if err != nil {
 			log.Println("error parsing question set for team", tc.Name, err)
 		} else {
+			qs.position = i
+			if qs.Name == "" {
+				qs.unnamedIndex = unnamed
+				unnamed++
+			}
 			qsets = append(qsets, qs)
 		}
 	}
 
+	channels := tc.Channels
+	if tc.Channel != "" {
+		channels = append([]string{tc.Channel}, channels...)
+	}
+
 	t := &Team{
-		Name:          tc.Name,
-		Channel:       tc.Channel,
-		Members:       tc.Members,
-		QuestionsSets: qsets,
-		SplitReport:   tc.SplitReport,
+		Name:                 tc.Name,
+		Channels:             channels,
+		Members:              mergeMembers(tc.Members, nil, tc.ExcludeMembers),
+		MembersFromUserGroup: tc.MembersFromUserGroup,
+		MembersFromChannel:   tc.MembersFromChannel,
+		IncludedMembers:      tc.Members,
+		ExcludedMembers:      tc.ExcludeMembers,
+		QuestionsSets:        qsets,
+		SplitReport:          tc.SplitReport,
+		Leads:                tc.Leads,
+		EscalationChannel:    tc.EscalationChannel,
+		Admins:               tc.Admins,
+		Webhooks:             toWebhooks(tc.Name, tc.Webhooks),
+	}
+
+	if tc.Digest != nil {
+		digest, err := tc.Digest.toDigest()
+		if err != nil {
+			log.Println("error parsing digest for team", tc.Name, err)
+		}
+		t.Digest = digest
 	}
 
 	if tc.Timezone != "" {
@@ -167,26 +359,147 @@ func (tc *TeamConfig) ToTeam() *Team {
 	return t
 }
 
+func hasQuestionSetNamed(qsets []*QuestionSet, name string) bool {
+	for _, qs := range qsets {
+		if strings.EqualFold(qs.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (dc *DigestConfig) toDigest() (*Digest, error) {
+	schedule, err := cron.Parse(dc.ScheduleCron)
+	if err != nil {
+		return nil, err
+	}
+
+	audience := Audience(dc.To)
+	switch audience {
+	case "":
+		audience = ChannelAudience
+	case ChannelAudience, LeadAudience:
+	default:
+		return nil, fmt.Errorf("a digest goes to the '%s' or the '%s', not '%s'", ChannelAudience, LeadAudience, dc.To)
+	}
+	return &Digest{Schedule: schedule, Audience: audience}, nil
+}
+
 func (qs *QuestionSetConfig) toQuestionSet() (*QuestionSet, error) {
 	schedule, err := cron.Parse(qs.ReportScheduleCron)
 	if err != nil {
 		return nil, err
 	}
 
-	fir, err := time.ParseDuration(qs.FirstReminderBeforeReport)
+	reminders, err := qs.reminders()
+	if err != nil {
+		return nil, err
+	}
+
+	var grace time.Duration
+	if qs.LateGracePeriod != "" {
+		grace, err = time.ParseDuration(qs.LateGracePeriod)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	window, err := qs.ReportWindow.toReportWindow()
 	if err != nil {
 		return nil, err
 	}
 
-	sec, err := time.ParseDuration(qs.LastReminderBeforeReport)
+	completion, err := newCompletionPolicy(qs.Completion, qs.Quorum)
 	if err != nil {
 		return nil, err
 	}
 
+	questions := make([]Question, len(qs.Questions))
+	ids := map[string]bool{}
+	for i, q := range qs.Questions {
+		id := q.ID
+		if id == "" {
+			id = fmt.Sprintf("q%d", i+1)
+		}
+		if ids[id] {
+			return nil, fmt.Errorf("duplicate question id '%s'", id)
+		}
+		ids[id] = true
+		if q.Type != "" && q.Type != MoodQuestion {
+			return nil, fmt.Errorf("unknown type '%s' for question '%s'", q.Type, id)
+		}
+		questions[i] = Question{ID: id, Text: q.Text, Blocker: q.Blocker, Type: q.Type, CarryFrom: q.CarryFrom, Compare: q.Compare}
+	}
+	for _, q := range questions {
+		if q.CarryFrom != "" && !ids[q.CarryFrom] {
+			return nil, fmt.Errorf("unknown question id '%s' to carry to '%s'", q.CarryFrom, q.ID)
+		}
+	}
+
+	destinations := make([]Destination, len(qs.Destinations))
+	for i, d := range qs.Destinations {
+		for _, id := range d.Questions {
+			if !ids[id] {
+				return nil, fmt.Errorf("unknown question id '%s' for channel '%s'", id, d.Channel)
+			}
+		}
+		destinations[i] = Destination{Channel: d.Channel, Questions: d.Questions}
+	}
+
 	return &QuestionSet{
-		Questions:                 qs.Questions,
-		ReportSchedule:            schedule,
-		FirstReminderBeforeReport: fir,
-		LastReminderBeforeReport:  sec,
+		Name:            qs.Name,
+		Description:     qs.Description,
+		Questions:       questions,
+		Destinations:    destinations,
+		ReportSchedule:  schedule,
+		Reminders:       reminders,
+		LateGracePeriod: grace,
+		ReportWindow:    window,
+		Completion:      completion,
+		Quorum:          qs.Quorum,
 	}, nil
 }
+
+func (rw *ReportWindowConfig) toReportWindow() (*ReportWindow, error) {
+	if rw == nil {
+		return nil, nil
+	}
+
+	opens, err := time.ParseDuration(rw.Opens)
+	if err != nil {
+		return nil, err
+	}
+	closes, err := time.ParseDuration(rw.Closes)
+	if err != nil {
+		return nil, err
+	}
+	if opens >= closes {
+		return nil, fmt.Errorf("report window closes (%s) before it opens (%s)", rw.Closes, rw.Opens)
+	}
+	return &ReportWindow{Opens: opens, Closes: closes}, nil
+}
+
+// reminders are the configured reminders, first_reminder_limit and last_reminder_limit
+// are the legacy way to configure a direct message and a channel reminder
+func (qs *QuestionSetConfig) reminders() ([]Reminder, error) {
+	configs := qs.Reminders
+	if qs.FirstReminderBeforeReport != "" {
+		configs = append(configs, ReminderConfig{Offset: qs.FirstReminderBeforeReport, Audience: string(DirectAudience)})
+	}
+	if qs.LastReminderBeforeReport != "" {
+		configs = append(configs, ReminderConfig{Offset: qs.LastReminderBeforeReport, Audience: string(ChannelAudience)})
+	}
+
+	reminders := make([]Reminder, len(configs))
+	for i, rc := range configs {
+		offset, err := time.ParseDuration(rc.Offset)
+		if err != nil {
+			return nil, err
+		}
+		reminders[i], err = NewReminder(offset, Audience(rc.Audience), rc.Template, rc.OpenReport)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reminders, nil
+}
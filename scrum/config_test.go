@@ -0,0 +1,159 @@
+package scrum
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestQuestionsCanBeTextOrObjectWithId(t *testing.T) {
+	qsc := QuestionSetConfig{}
+	err := json.Unmarshal([]byte(`{
+		"questions": ["What did you do yesterday?", {"id": "today", "text": "What will you do today?"}],
+		"report_schedule_cron": "0 5 9 * * 1-5",
+		"first_reminder_limit": "-50m",
+		"last_reminder_limit": "-5m"
+	}`), &qsc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qs, err := qsc.toQuestionSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qs.Questions[0].ID != "q1" || qs.Questions[0].Text != "What did you do yesterday?" {
+		t.Fail()
+	}
+	if qs.Questions[1].ID != "today" || qs.Questions[1].Text != "What will you do today?" {
+		t.Fail()
+	}
+}
+
+func TestDuplicateQuestionIdsAreRejected(t *testing.T) {
+	qsc := QuestionSetConfig{
+		Questions:                 []QuestionConfig{{ID: "today", Text: "What will you do today?"}, {ID: "today", Text: "And tomorrow?"}},
+		ReportScheduleCron:        "0 5 9 * * 1-5",
+		FirstReminderBeforeReport: "-50m",
+		LastReminderBeforeReport:  "-5m",
+	}
+
+	_, err := qsc.toQuestionSet()
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestQuestionsAreCarriedFromKnownQuestions(t *testing.T) {
+	qsc := QuestionSetConfig{
+		Questions:          []QuestionConfig{{ID: "yesterday", Text: "What did you do yesterday?", CarryFrom: "tomorrow"}, {ID: "today", Text: "What will you do today?"}},
+		ReportScheduleCron: "0 5 9 * * 1-5",
+	}
+
+	if _, err := qsc.toQuestionSet(); err == nil {
+		t.Error("the question carried from does not exist")
+	}
+
+	qsc.Questions[0].CarryFrom = "today"
+	qs, err := qsc.toQuestionSet()
+	if err != nil || qs.Questions[0].CarryFrom != "today" {
+		t.Error(err)
+	}
+}
+
+func TestWebhooksAreValidated(t *testing.T) {
+	webhooks := toWebhooks("L337", []WebhookConfig{
+		{URL: "https://example.com/standups", Secret: "s3cr3t"},
+		{URL: "example.com/standups"},
+		{URL: "https://example.com/posted", Events: []string{ReportPostedEvent}},
+		{URL: "https://example.com/posted", Events: []string{"report_deleted"}},
+	})
+	if len(webhooks) != 2 || webhooks[0].Secret != "s3cr3t" || webhooks[1].Events[0] != ReportPostedEvent {
+		t.Error("unexpected webhooks", webhooks)
+	}
+}
+
+func TestLegacyReminderLimitsAreReminders(t *testing.T) {
+	qsc := QuestionSetConfig{
+		Reminders:                 []ReminderConfig{{Offset: "-15m", Audience: "lead", Template: "{{.Missing}} are late"}},
+		ReportScheduleCron:        "0 5 9 * * 1-5",
+		FirstReminderBeforeReport: "-50m",
+		LastReminderBeforeReport:  "-5m",
+	}
+
+	qs, err := qsc.toQuestionSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(qs.Reminders) != 3 {
+		t.Fatalf("expected 3 reminders, got %d", len(qs.Reminders))
+	}
+	if qs.Reminders[0].Audience != LeadAudience || qs.Reminders[0].Offset != -15*time.Minute {
+		t.Error("configured reminder not kept", qs.Reminders[0])
+	}
+	if qs.Reminders[1].Audience != DirectAudience || qs.Reminders[1].Offset != -50*time.Minute {
+		t.Error("first reminder limit is not a direct reminder", qs.Reminders[1])
+	}
+	if qs.Reminders[2].Audience != ChannelAudience || qs.Reminders[2].Offset != -5*time.Minute {
+		t.Error("last reminder limit is not a channel reminder", qs.Reminders[2])
+	}
+}
+
+func TestUnknownReminderAudienceIsRejected(t *testing.T) {
+	qsc := QuestionSetConfig{
+		Reminders:          []ReminderConfig{{Offset: "-15m", Audience: "everyone"}},
+		ReportScheduleCron: "0 5 9 * * 1-5",
+	}
+
+	_, err := qsc.toQuestionSet()
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestOnlyDirectRemindersCanOpenTheReport(t *testing.T) {
+	if _, err := NewReminder(-time.Hour, DirectAudience, "", true); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewReminder(-time.Hour, ChannelAudience, "", true); err == nil {
+		t.Fail()
+	}
+}
+
+func TestQuorumMustBeAPercentage(t *testing.T) {
+	if _, err := newCompletionPolicy("quorum", 0); err == nil {
+		t.Fail()
+	}
+	if _, err := newCompletionPolicy("sometimes", 0); err == nil {
+		t.Fail()
+	}
+	if policy, err := newCompletionPolicy("", 0); err != nil || policy != CompleteWhenAllReported {
+		t.Error("unexpected default policy", policy)
+	}
+}
+
+func TestTeamRolesAreConfigured(t *testing.T) {
+	tc := TeamConfig{Name: "L337", Leads: []string{"pa"}, Admins: []string{"jo"}}
+
+	team := tc.ToTeam()
+	if len(team.Leads) != 1 || team.Leads[0] != "pa" || len(team.Admins) != 1 || team.Admins[0] != "jo" {
+		t.Error("unexpected roles", team.Leads, team.Admins)
+	}
+}
+
+func TestQuestionSetNamesAreUniqueInATeam(t *testing.T) {
+	qsc := QuestionSetConfig{Questions: []QuestionConfig{{Text: "What will you do today?"}}, ReportScheduleCron: "0 5 9 * * 1-5"}
+	daily, retro := qsc, qsc
+	daily.Name, retro.Name = "daily", "retro"
+	tc := TeamConfig{Name: "L337", QuestionSets: []QuestionSetConfig{qsc, daily, qsc, retro, daily}}
+
+	team := tc.ToTeam()
+	if len(team.QuestionsSets) != 4 || team.QuestionsSets[1].Name != "daily" || team.QuestionsSets[3].Name != "retro" {
+		t.Fatal("unexpected question sets", team.QuestionsSets)
+	}
+	if first, second := team.QuestionsSets[0], team.QuestionsSets[2]; first.Name != "" || second.Name != "" || first.key() == second.key() {
+		t.Error("the unnamed question sets are not told apart", first.key(), second.key())
+	}
+}
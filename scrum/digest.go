@@ -0,0 +1,111 @@
+package scrum
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// DigestJob posts the weekly digest of a team
+type DigestJob struct {
+	*TeamState
+}
+
+func (job *DigestJob) Run() {
+	if job.TeamState.paused {
+		return
+	}
+
+	job.TeamState.sendDigest(time.Now())
+}
+
+// sendDigest compiles the reports of the members for the last seven days and
+// posts them to the team channels or to the leads
+func (ts *TeamState) sendDigest(now time.Time) {
+	logger := log.WithFields(log.Fields{
+		"team": ts.Team.Name,
+	})
+
+	now = now.In(ts.location)
+	reports, err := ts.service.store.Reports(ReportFilter{Teams: []string{ts.Team.Name}, From: now.AddDate(0, 0, -6), To: now})
+	if err != nil {
+		logger.WithField("error", err).Error("Cannot read the reports of the digest.")
+		return
+	}
+
+	params := slack.PostMessageParameters{AsUser: true}
+	message := ":newspaper: Nothing was reported this week, see you next week!"
+	if len(reports) > 0 {
+		message = ":newspaper: Here's the digest of the week for team " + ts.Team.Name
+		for _, member := range ts.Members {
+			params.Attachments = append(params.Attachments, slack.Attachment{
+				Color:      colorful.FastHappyColor().Hex(),
+				MarkdownIn: []string{"text", "pretext"},
+				Pretext:    "@" + ts.service.users.UserName(member),
+				Text:       digestText(reports, member),
+			})
+		}
+	}
+
+	recipients := ts.Channels
+	if ts.Digest.Audience == LeadAudience {
+		recipients = ts.Leads
+	}
+	for _, recipient := range recipients {
+		ts.postMessageToSlack(recipient, message, params)
+	}
+	logger.Info("Sent weekly digest.")
+}
+
+// digestText lists the reports of a member, a line per day the team reported on
+func digestText(reports []*Report, member string) string {
+	dates := []string{}
+	byDate := map[string][]*Report{}
+	for _, report := range reports {
+		date := report.Date.Format(DateFormat)
+		if _, ok := byDate[date]; !ok {
+			dates = append(dates, date)
+			byDate[date] = []*Report{}
+		}
+		if report.User == member {
+			byDate[date] = append(byDate[date], report)
+		}
+	}
+	sort.Strings(dates)
+
+	lines := []string{}
+	for _, date := range dates {
+		day, _ := time.Parse(DateFormat, date)
+		label := "*" + day.Format("Mon 01-02") + "*"
+
+		if len(byDate[date]) == 0 {
+			lines = append(lines, label+" :x: no report")
+			continue
+		}
+		for _, report := range byDate[date] {
+			title := label
+			if report.QuestionSet != "" {
+				title += " (" + report.QuestionSet + ")"
+			}
+			switch {
+			case report.OutOfOffice:
+				lines = append(lines, title+" :palm_tree: out of office")
+			case report.Skipped:
+				lines = append(lines, title+" :zzz: skipped")
+			default:
+				lines = append(lines, title)
+				for _, question := range report.Questions {
+					if answer, ok := report.Answers[question.ID]; ok {
+						lines = append(lines, fmt.Sprintf("> _%s_ %s", question.Text, strings.Replace(question.FormatAnswer(answer), "\n", " ", -1)))
+					}
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
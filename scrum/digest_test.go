@@ -0,0 +1,21 @@
+package scrum
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestHasALinePerDay(t *testing.T) {
+	monday := time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC)
+	questions := []Question{{ID: "today", Text: "Today?"}}
+	reports := []*Report{
+		{User: "U1", Date: monday, Questions: questions, Answers: map[string]string{"today": "Deploy"}},
+		{User: "U2", Date: monday.AddDate(0, 0, 1), Skipped: true, Answers: map[string]string{}},
+		{User: "U1", Date: monday.AddDate(0, 0, 2), Skipped: true, OutOfOffice: true, Answers: map[string]string{}},
+	}
+
+	expected := "*Mon 03-12*\n> _Today?_ Deploy\n*Tue 03-13* :x: no report\n*Wed 03-14* :palm_tree: out of office"
+	if text := digestText(reports, "U1"); text != expected {
+		t.Error("unexpected digest", text)
+	}
+}
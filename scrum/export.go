@@ -0,0 +1,192 @@
+package scrum
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormats are the formats reports can be exported to
+var ExportFormats = []string{"csv", "json", "md"}
+
+// exportedReport is the json export of a report
+type exportedReport struct {
+	Date        string            `json:"date"`
+	Team        string            `json:"team"`
+	QuestionSet string            `json:"question_set,omitempty"`
+	User        string            `json:"user"`
+	ReportedBy  string            `json:"reported_by,omitempty"`
+	Skipped     bool              `json:"skipped"`
+	OutOfOffice bool              `json:"out_of_office"`
+	SubmittedAt *time.Time        `json:"submitted_at,omitempty"`
+	Answers     map[string]string `json:"answers"`
+}
+
+// ExportReports writes the reports, oldest first, one per member and window. The users
+// are shown with userName, the answers are in a column per question id.
+func ExportReports(w io.Writer, format string, reports []*Report, userName func(id string) string) error {
+	reports = append([]*Report{}, reports...)
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if c := compareDates(a.Date, b.Date); c != 0 {
+			return c < 0
+		}
+		if a.Team != b.Team {
+			return a.Team < b.Team
+		}
+		if a.QuestionSet != b.QuestionSet {
+			return a.QuestionSet < b.QuestionSet
+		}
+		return a.User < b.User
+	})
+
+	switch format {
+	case "csv":
+		return exportCSV(w, reports, userName)
+	case "json":
+		return exportJSON(w, reports, userName)
+	case "md":
+		return exportMarkdown(w, reports, userName)
+	default:
+		return fmt.Errorf("unknown export format '%s', use one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// questionIDs are the ids of the questions answered in the reports, in the order they were asked
+func questionIDs(reports []*Report) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, report := range reports {
+		for _, question := range report.Questions {
+			if !seen[question.ID] {
+				seen[question.ID] = true
+				ids = append(ids, question.ID)
+			}
+		}
+		// answers of questions missing from the report, sorted to stay stable
+		extra := []string{}
+		for id := range report.Answers {
+			if !seen[id] {
+				extra = append(extra, id)
+			}
+		}
+		sort.Strings(extra)
+		for _, id := range extra {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func submittedAt(report *Report) string {
+	if report.SubmittedAt.IsZero() {
+		return ""
+	}
+	return report.SubmittedAt.Format(time.RFC3339)
+}
+
+func reportedByName(report *Report, userName func(id string) string) string {
+	if report.ReportedBy == "" {
+		return ""
+	}
+	return userName(report.ReportedBy)
+}
+
+func exportCSV(w io.Writer, reports []*Report, userName func(id string) string) error {
+	ids := questionIDs(reports)
+	out := csv.NewWriter(w)
+
+	header := append([]string{"date", "team", "question_set", "user", "reported_by", "skipped", "out_of_office", "submitted_at"}, ids...)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		row := []string{
+			report.Date.Format(DateFormat),
+			report.Team,
+			report.QuestionSet,
+			userName(report.User),
+			reportedByName(report, userName),
+			strconv.FormatBool(report.Skipped),
+			strconv.FormatBool(report.OutOfOffice),
+			submittedAt(report),
+		}
+		for _, id := range ids {
+			row = append(row, report.Answers[id])
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// exportReport is the json export of a report, also sent to the webhooks
+func exportReport(report *Report, userName func(id string) string) exportedReport {
+	exported := exportedReport{
+		Date:        report.Date.Format(DateFormat),
+		Team:        report.Team,
+		QuestionSet: report.QuestionSet,
+		User:        userName(report.User),
+		ReportedBy:  reportedByName(report, userName),
+		Skipped:     report.Skipped,
+		OutOfOffice: report.OutOfOffice,
+		Answers:     report.Answers,
+	}
+	if !report.SubmittedAt.IsZero() {
+		exported.SubmittedAt = &report.SubmittedAt
+	}
+	return exported
+}
+
+func exportJSON(w io.Writer, reports []*Report, userName func(id string) string) error {
+	exported := make([]exportedReport, len(reports))
+	for i, report := range reports {
+		exported[i] = exportReport(report, userName)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+// exportMarkdown writes a section per window with a sub section per member
+func exportMarkdown(w io.Writer, reports []*Report, userName func(id string) string) error {
+	section := ""
+	for _, report := range reports {
+		title := report.Date.Format(DateFormat) + " - " + report.Team
+		if report.QuestionSet != "" {
+			title += " (" + report.QuestionSet + ")"
+		}
+		if title != section {
+			section = title
+			if _, err := fmt.Fprintf(w, "## %s\n\n", title); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(w, "### %s\n\n", userName(report.User))
+		if report.OutOfOffice {
+			fmt.Fprint(w, "Was out of office.\n\n")
+			continue
+		}
+		if report.Skipped {
+			fmt.Fprint(w, "Has nothing to declare.\n\n")
+			continue
+		}
+		for _, question := range report.Questions {
+			if answer, ok := report.Answers[question.ID]; ok {
+				fmt.Fprintf(w, "**%s**\n\n%s\n\n", question.Text, answer)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package scrum
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCSVHasAColumnPerQuestion(t *testing.T) {
+	monday := time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC)
+	questions := []Question{{ID: "yesterday", Text: "What did you do yesterday?"}, {ID: "today", Text: "What will you do today?"}}
+	reports := []*Report{
+		{User: "U2", Team: "L337", Date: monday.AddDate(0, 0, 1), Questions: questions, Answers: map[string]string{"yesterday": "Deploy", "today": "Reviews"}},
+		{User: "U1", Team: "L337", Date: monday, Skipped: true, Answers: map[string]string{}},
+		{User: "U3", Team: "L337", Date: monday, Skipped: true, OutOfOffice: true, Answers: map[string]string{}},
+	}
+
+	out := bytes.Buffer{}
+	if err := ExportReports(&out, "csv", reports, strings.ToLower); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "date,team,question_set,user,reported_by,skipped,out_of_office,submitted_at,yesterday,today\n" +
+		"2018-03-12,L337,,u1,,true,false,,,\n" +
+		"2018-03-12,L337,,u3,,true,true,,,\n" +
+		"2018-03-13,L337,,u2,,false,false,,Deploy,Reviews\n"
+	if out.String() != expected {
+		t.Error("unexpected export", out.String())
+	}
+
+	if err := ExportReports(&out, "xls", reports, strings.ToLower); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,129 @@
+package scrum
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// MembersSyncJob refreshes the members of a team from its slack user group and channel
+type MembersSyncJob struct {
+	*TeamState
+}
+
+func (job *MembersSyncJob) Run() {
+	job.TeamState.syncMembers()
+}
+
+func (ts *TeamState) syncsMembers() bool {
+	return ts.MembersFromUserGroup != "" || ts.MembersFromChannel != ""
+}
+
+// syncMembers merges the members of the slack user group and channel of the team
+// with its explicitly included members, minus the excluded ones
+func (ts *TeamState) syncMembers() {
+	if !ts.syncsMembers() {
+		return
+	}
+
+	logger := log.WithFields(log.Fields{
+		"team":      ts.Team.Name,
+		"usergroup": ts.MembersFromUserGroup,
+		"channel":   ts.MembersFromChannel,
+	})
+
+	ids := []string{}
+	if ts.MembersFromUserGroup != "" {
+		groupMembers, err := ts.service.userGroupMembers(ts.MembersFromUserGroup)
+		if err != nil {
+			logger.WithField("error", err).Warn("Cannot get user group members, keeping current members.")
+			return
+		}
+		ids = append(ids, groupMembers...)
+	}
+	if ts.MembersFromChannel != "" {
+		channelMembers, err := ts.service.channelMembers(ts.MembersFromChannel)
+		if err != nil {
+			logger.WithField("error", err).Warn("Cannot get channel members, keeping current members.")
+			return
+		}
+		ids = append(ids, channelMembers...)
+	}
+
+	synced := []string{}
+	for _, id := range ids {
+		// skips bots and deactivated users
+		if _, ok := ts.service.users.UserID(id); ok {
+			synced = append(synced, id)
+		}
+	}
+	sort.Strings(synced)
+
+	ts.Members = mergeMembers(ts.IncludedMembers, synced, ts.ExcludedMembers)
+	logger.WithField("members", ts.Members).Info("Team members synchronized.")
+}
+
+// mergeMembers returns the included and synced members, without duplicates nor excluded ones
+func mergeMembers(included []string, synced []string, excluded []string) []string {
+	seen := map[string]bool{}
+	for _, member := range excluded {
+		seen[member] = true
+	}
+
+	members := []string{}
+	for _, member := range append(append([]string{}, included...), synced...) {
+		if !seen[member] {
+			seen[member] = true
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// userGroupMembers lists the user ids of a user group given by id or handle
+func (mod *service) userGroupMembers(userGroup string) ([]string, error) {
+	groups, err := mod.slackBotAPI.GetUserGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	id := userGroup
+	for _, group := range groups {
+		if group.Handle == strings.TrimLeft(userGroup, "@") {
+			id = group.ID
+		}
+	}
+
+	return mod.slackBotAPI.GetUserGroupMembers(id)
+}
+
+// channelMembers lists the user ids of a channel given by id or name
+func (mod *service) channelMembers(channel string) ([]string, error) {
+	channels, err := mod.slackBotAPI.GetChannels(true)
+	if err != nil {
+		return nil, err
+	}
+
+	id := channel
+	for _, c := range channels {
+		if c.Name == strings.TrimLeft(channel, "#") {
+			id = c.ID
+		}
+	}
+
+	members := []string{}
+	params := &slack.GetUsersInConversationParameters{ChannelID: id, Limit: 200}
+	for {
+		page, cursor, err := mod.slackBotAPI.GetUsersInConversation(params)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		if cursor == "" {
+			return members, nil
+		}
+		params.Cursor = cursor
+	}
+}
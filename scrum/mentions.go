@@ -0,0 +1,94 @@
+package scrum
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// mentionRegex matches the slack user mentions, <@U123> or <@U123|name>
+var mentionRegex = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// mentionedUsers are the users mentioned in the answers of a report, but its authors
+func mentionedUsers(report *Report) []string {
+	seen := map[string]bool{report.User: true, report.ReportedBy: true}
+	users := []string{}
+	for _, q := range report.Questions {
+		for _, match := range mentionRegex.FindAllStringSubmatch(report.Answers[q.ID], -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				users = append(users, match[1])
+			}
+		}
+	}
+	return users
+}
+
+// mentions tells if an answer mentions a user, <@U2> doesn't mention U23
+func mentions(answer string, user string) bool {
+	for _, match := range mentionRegex.FindAllStringSubmatch(answer, -1) {
+		if match[1] == user {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyMentions tells the users mentioned in a report where it was posted, with the answers mentioning them
+func (ts *TeamState) notifyMentions(qs *QuestionSet, report *Report, posted map[string]postedMessage) {
+	users := mentionedUsers(report)
+	if len(users) == 0 || report.Skipped {
+		return
+	}
+
+	link := ""
+	for _, destination := range ts.destinations(qs) {
+		if message, ok := posted[destination.Channel]; ok && message.timestamp != "" {
+			link = ts.service.messageLink(message)
+			break
+		}
+	}
+
+	for _, user := range users {
+		answers := []string{}
+		for _, q := range report.Questions {
+			answer := report.Answers[q.ID]
+			if mentions(answer, user) {
+				answers = append(answers, "> *"+q.Text+"* "+strings.Replace(answer, "\n", " ", -1))
+			}
+		}
+
+		message := fmt.Sprintf("%s mentioned you in their scrum report of %s for team %s:\n%s", mention(report.User), report.Date.Format(DateFormat), ts.Team.Name, strings.Join(answers, "\n"))
+		if link != "" {
+			message += "\n" + link
+		}
+		_, _, err := ts.service.slackBotAPI.PostMessage(user, message, slack.PostMessageParameters{AsUser: true})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"team":  ts.Team.Name,
+				"user":  user,
+				"error": err,
+			}).Warn("Could not notify mentioned user.")
+		}
+	}
+}
+
+// messageLink links to a posted message, or to its channel when the slack team domain is not known
+func (m *service) messageLink(message postedMessage) string {
+	m.teamDomainOnce.Do(func() {
+		info, err := m.slackBotAPI.GetTeamInfo()
+		if err != nil {
+			log.WithField("error", err).Warn("Cannot get the slack team domain, linking to the channels instead.")
+			return
+		}
+		m.teamDomain = info.Domain
+	})
+
+	if m.teamDomain == "" {
+		return "<#" + message.channelID + ">"
+	}
+	return fmt.Sprintf("https://%s.slack.com/archives/%s/p%s", m.teamDomain, message.channelID, strings.Replace(message.timestamp, ".", "", 1))
+}
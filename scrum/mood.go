@@ -0,0 +1,153 @@
+package scrum
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MoodQuestion is the type of the questions answered on a scale from 1 to 5
+const MoodQuestion = "mood"
+
+// moodEmojis are the answers of the mood scale, from 1 to 5
+var moodEmojis = []string{":tired_face:", ":slightly_frowning_face:", ":neutral_face:", ":slightly_smiling_face:", ":smile:"}
+
+// sparks render the mood trends, from 1 to 5
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// MoodWeek is the average mood of a team during a week
+type MoodWeek struct {
+	Start   time.Time
+	Average float64
+	// Answers is the number of mood answers, there is no average without any
+	Answers int
+}
+
+// IsMood tells if a question is answered on the mood scale
+func (q Question) IsMood() bool {
+	return q.Type == MoodQuestion
+}
+
+// MoodScale lists the answers of a mood question
+func MoodScale() string {
+	choices := make([]string, len(moodEmojis))
+	for i, emoji := range moodEmojis {
+		choices[i] = fmt.Sprintf("%d %s", i+1, emoji)
+	}
+	return strings.Join(choices, "   ")
+}
+
+// ParseMood reads a mood answer, a number from 1 to 5 or one of the emojis of the scale
+func ParseMood(answer string) (int, bool) {
+	answer = strings.TrimSpace(answer)
+	if mood, err := strconv.Atoi(answer); err == nil {
+		return mood, mood >= 1 && mood <= len(moodEmojis)
+	}
+	for i, emoji := range moodEmojis {
+		if answer == emoji {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func moodEmoji(mood float64) string {
+	i := int(mood+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(moodEmojis) {
+		i = len(moodEmojis) - 1
+	}
+	return moodEmojis[i]
+}
+
+// FormatAnswer shows an answer to the question, with its emoji for a mood
+func (q Question) FormatAnswer(answer string) string {
+	if !q.IsMood() {
+		return answer
+	}
+	mood, ok := ParseMood(answer)
+	if !ok {
+		return answer
+	}
+	return fmt.Sprintf("%s %d/%d", moodEmoji(float64(mood)), mood, len(moodEmojis))
+}
+
+// averageMood is the average of the mood answers of the reports
+func averageMood(reports []*Report) (float64, int) {
+	total, count := 0, 0
+	for _, report := range reports {
+		for _, q := range report.Questions {
+			if !q.IsMood() {
+				continue
+			}
+			if mood, ok := ParseMood(report.Answers[q.ID]); ok {
+				total += mood
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return float64(total) / float64(count), count
+}
+
+// moodTrend averages the mood answers of the reports by week, the last week ending at now
+func moodTrend(reports []*Report, weeks int, now time.Time) []MoodWeek {
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -7*weeks)
+
+	byWeek := make([][]*Report, weeks)
+	for _, report := range reports {
+		date := time.Date(report.Date.Year(), report.Date.Month(), report.Date.Day(), 0, 0, 0, 0, now.Location())
+		if date.Before(start) || !date.Before(end) {
+			continue
+		}
+		week := daysBetween(start, date) / 7
+		byWeek[week] = append(byWeek[week], report)
+	}
+
+	trend := make([]MoodWeek, weeks)
+	for i := range trend {
+		trend[i].Start = start.AddDate(0, 0, 7*i)
+		trend[i].Average, trend[i].Answers = averageMood(byWeek[i])
+	}
+	return trend
+}
+
+// daysBetween counts the calendar days from a date to another, the days are not all
+// 24 hours long when the daylight saving time changes
+func daysBetween(from, to time.Time) int {
+	utc := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	return int(utc(to).Sub(utc(from)).Hours() / 24)
+}
+
+// Sparkline renders the average moods of the weeks, the weeks without answers are dots
+func Sparkline(trend []MoodWeek) string {
+	line := []rune{}
+	for _, week := range trend {
+		if week.Answers == 0 {
+			line = append(line, '·')
+			continue
+		}
+		i := int((week.Average - 1) / float64(len(moodEmojis)-1) * float64(len(sparks)-1))
+		line = append(line, sparks[i])
+	}
+	return string(line)
+}
+
+// moodAttachmentText is the team average of the mood answers of a report, if any
+func moodAttachmentText(reports map[string]*Report) (string, bool) {
+	list := make([]*Report, 0, len(reports))
+	for _, report := range reports {
+		list = append(list, report)
+	}
+	average, count := averageMood(list)
+	if count == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s %.1f/%d from %d answers", moodEmoji(average), average, len(moodEmojis), count), true
+}
@@ -0,0 +1,180 @@
+package scrum
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Audience is who a reminder is sent to
+type Audience string
+
+const (
+	// DirectAudience reminds each member who hasn't reported yet in a direct message
+	DirectAudience Audience = "dm"
+	// ChannelAudience pings the members who haven't reported yet in the full report channels
+	ChannelAudience Audience = "channel"
+	// LeadAudience tells the team leads who hasn't reported yet in a direct message
+	LeadAudience Audience = "lead"
+)
+
+// defaultReminderTemplates are used by the reminders configured without a template
+var defaultReminderTemplates = map[Audience]string{
+	DirectAudience:  "Hey! Don't forget to fill your report! `start` to do it or `skip` if you have nothing to say, or `snooze 30m` to be reminded later",
+	ChannelAudience: "Last chance to fill report! :shame: to: {{.Missing}}",
+	LeadAudience:    "These members of {{.Team}} haven't filled their report yet: {{.Missing}}",
+}
+
+// openReportTemplate is the default of the direct reminders opening the questionnaire
+const openReportTemplate = "Hey! Time to fill your report for {{.Team}}, just answer the questions below or `quit` to do it later"
+
+// ReminderData is given to the reminder templates
+type ReminderData struct {
+	Team        string
+	QuestionSet string
+	// Member is the mention of the reminded member, only set for direct messages
+	Member string
+	// Missing are the mentions of the members who haven't reported yet, comma separated
+	Missing string
+	// Deadline is the time of the report in the team timezone
+	Deadline string
+}
+
+// NewReminder parses the template of a reminder, an empty template uses the audience default.
+// Only direct reminders can open the questionnaire.
+func NewReminder(offset time.Duration, audience Audience, text string, openReport bool) (Reminder, error) {
+	defaultText, ok := defaultReminderTemplates[audience]
+	if !ok {
+		return Reminder{}, fmt.Errorf("unknown reminder audience '%s'", audience)
+	}
+	if openReport && audience != DirectAudience {
+		return Reminder{}, fmt.Errorf("only '%s' reminders can open the report", DirectAudience)
+	}
+	if openReport {
+		defaultText = openReportTemplate
+	}
+	if text == "" {
+		text = defaultText
+	}
+
+	tmpl, err := template.New(string(audience)).Parse(text)
+	if err != nil {
+		return Reminder{}, err
+	}
+	return Reminder{Offset: offset, Audience: audience, Template: tmpl, OpenReport: openReport}, nil
+}
+
+// ScrumReminderJob sends one of the reminders of a question set
+type ScrumReminderJob struct {
+	Reminder
+	*TeamState
+	*QuestionSet
+}
+
+func (job *ScrumReminderJob) Run() {
+	if job.TeamState.paused {
+		return
+	}
+
+	job.TeamState.sendReminder(job.QuestionSet, job.Reminder)
+}
+
+// missingMembers are the members in office who haven't reported yet
+func (ts *TeamState) missingMembers(qsstate *questionSetState) []string {
+	missing := []string{}
+	for _, member := range ts.Members {
+		if isMemberOutOfOffice(ts, member) {
+			continue
+		}
+		if _, ok := qsstate.enteredReports[member]; !ok {
+			missing = append(missing, member)
+		}
+	}
+	return missing
+}
+
+func (ts *TeamState) sendReminder(qs *QuestionSet, reminder Reminder) {
+	qsstate, ok := ts.questionSetState(qs)
+	if !ok {
+		return
+	}
+
+	logger := log.WithFields(log.Fields{
+		"team":     ts.Team.Name,
+		"audience": reminder.Audience,
+		"offset":   reminder.Offset,
+	})
+
+	missing := ts.missingMembers(qsstate)
+	if len(missing) == 0 {
+		logger.Info("Everybody reported, not sending reminder.")
+		return
+	}
+	logger.Info("Sending reminder.")
+
+	mentions := make([]string, len(missing))
+	for i, member := range missing {
+		mentions[i] = mention(member)
+	}
+	data := ReminderData{
+		Team:        ts.Team.Name,
+		QuestionSet: qs.Name,
+		Missing:     strings.Join(mentions, ", "),
+		Deadline:    qs.ReportSchedule.Next(time.Now().In(ts.location)).Format("15:04"),
+	}
+
+	switch reminder.Audience {
+	case DirectAudience:
+		for _, member := range missing {
+			data.Member = mention(member)
+			ts.sendReminderMessage(reminder, member, data)
+			if reminder.OpenReport {
+				ts.openReport(member, qs)
+			}
+		}
+	case ChannelAudience:
+		for _, destination := range ts.destinations(qs) {
+			if destination.IsFullReport() {
+				ts.sendReminderMessage(reminder, destination.Channel, data)
+			}
+		}
+	case LeadAudience:
+		if len(ts.Leads) == 0 {
+			logger.Warn("Team has no leads to remind.")
+		}
+		for _, lead := range ts.Leads {
+			ts.sendReminderMessage(reminder, lead, data)
+		}
+	}
+	ts.reminderWebhook(qs, reminder, missing)
+}
+
+// openReport asks the handlers to start the questionnaire of the question set with the member
+func (ts *TeamState) openReport(member string, qs *QuestionSet) {
+	for _, handler := range ts.service.openReportHandlers {
+		go handler(member, ts.Team.Name, qs)
+	}
+}
+
+func (ts *TeamState) sendReminderMessage(reminder Reminder, channel string, data ReminderData) {
+	logger := log.WithFields(log.Fields{
+		"team":     ts.Team.Name,
+		"audience": reminder.Audience,
+		"channel":  channel,
+	})
+
+	text := bytes.Buffer{}
+	if err := reminder.Template.Execute(&text, data); err != nil {
+		logger.WithField("error", err).Warn("Could not render reminder.")
+		return
+	}
+
+	_, _, err := ts.service.slackBotAPI.PostMessage(channel, text.String(), SlackParams)
+	if err != nil {
+		logger.WithField("error", err).Warn("Could not send reminder.")
+	}
+}
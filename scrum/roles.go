@@ -0,0 +1,51 @@
+package scrum
+
+// Role of a user in a team, each role can do what the previous ones can
+type Role int
+
+const (
+	// MemberRole can only act for themself
+	MemberRole Role = iota
+	// LeadRole can also act on behalf of the members of the team
+	LeadRole
+	// AdminRole can also force the team reports and pause the team
+	AdminRole
+)
+
+func (r Role) String() string {
+	switch r {
+	case LeadRole:
+		return "lead"
+	case AdminRole:
+		return "admin"
+	default:
+		return "member"
+	}
+}
+
+func contains(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *service) GetRole(team string, userID string) Role {
+	if contains(m.admins, userID) {
+		return AdminRole
+	}
+
+	ts, ok := m.teamStates[team]
+	if !ok {
+		return MemberRole
+	}
+	if contains(ts.Admins, userID) {
+		return AdminRole
+	}
+	if contains(ts.Leads, userID) {
+		return LeadRole
+	}
+	return MemberRole
+}
@@ -3,7 +3,9 @@ package scrum
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	colorful "github.com/lucasb-eyer/go-colorful"
@@ -15,20 +17,55 @@ import (
 var SlackParams = slack.PostMessageParameters{AsUser: true, LinkNames: 1}
 
 type Service interface {
-	DeleteLastReport(username string) bool
+	DeleteLastReport(userID string) bool
 	GetTeamByName(teamName string) (*TeamState, error)
-	GetTeamsForUser(username string) []string
+	GetTeamsForUser(userID string) []string
 	GetQuestionSetsForTeam(team string) []*QuestionSet
-	SaveReport(report *Report, qs *QuestionSet)
-	AddToOutOfOffice(team string, username string)
-	RemoveFromOutOfOffice(team string, username string)
+	SaveReport(report *Report, qs *QuestionSet) error
+	GetReportWindow(team string, qs *QuestionSet, date time.Time) (Window, error)
+	ResolveBlocker(userID string, id int) (*Blocker, error)
+	AddToOutOfOffice(team string, userID string)
+	RemoveFromOutOfOffice(team string, userID string)
+	GetRole(team string, userID string) Role
+	ForceReport(team string) error
+	SetPaused(team string, paused bool) error
+	GetPendingReports(userID string) []PendingReport
+	OnOpenReport(handler func(userID string, team string, qs *QuestionSet))
+	GetReports(filter ReportFilter) ([]*Report, error)
+	GetStats(team string, since time.Time) (*TeamStats, error)
+	GetMoodTrend(team string, weeks int) ([]MoodWeek, error)
+	GetCarriedAnswer(report *Report, qs *QuestionSet, question Question) (string, bool)
+	ReloadUsers()
+}
+
+// UserDirectory resolves slack users, members are identified by their user id
+type UserDirectory interface {
+	// UserID finds the id of a user from its id, mention, name or display name
+	UserID(user string) (string, bool)
+	// UserName is the name to display for a user id
+	UserName(id string) string
+	// UserLocation is the timezone of a user, nil when it is not known
+	UserLocation(id string) *time.Location
 }
 
 type service struct {
 	configurationProvider ConfigurationProvider
 	teamStates            map[string]*TeamState
 	slackBotAPI           *slack.Client
+	users                 UserDirectory
+	admins                []string
 	lastEnteredReport     map[string]*Report
+	// reports deleted by restart, they are kept as the previous versions of the next report
+	restartedReports   map[string]*Report
+	blockers           *blockerRegistry
+	store              ReportStore
+	openReportHandlers []func(userID string, team string, qs *QuestionSet)
+	// slack team domain, for the links to the posted reports
+	teamDomain     string
+	teamDomainOnce sync.Once
+	// webhooks receiving the events of every team
+	webhooks      []Webhook
+	webhookClient *webhookClient
 }
 
 type TeamState struct {
@@ -37,24 +74,106 @@ type TeamState struct {
 	*service
 
 	questionSetStates map[*QuestionSet]*questionSetState
+	// a paused team gets neither reminders nor reports
+	paused bool
+	// location of the report schedules
+	location *time.Location
 }
 
 type questionSetState struct {
 	*QuestionSet
 	enteredReports map[string]*Report
 	sent           bool
+	// reports entered in advance for a future report
+	upcomingReports []*Report
+	// window of the report
+	window Window
+	// report posted for this state and for the previous one, late reports go in their threads
+	posted         *postedReport
+	previousPosted *postedReport
+}
+
+// postedReport is a report posted to slack
+type postedReport struct {
+	window Window
+	sentAt time.Time
+	// first message of the report, by destination channel
+	threads map[string]postedMessage
+}
+
+type postedMessage struct {
+	channelID string
+	timestamp string
 }
 
 type Report struct {
-	User    string
-	Team    string
-	Skipped bool
-	// questions / answers
+	// User is the slack user id of the member
+	User string
+	// ReportedBy is the slack user id of the lead who entered the report on behalf of the member, if any
+	ReportedBy string
+	Team       string
+	// QuestionSet is the name of the set of questions answered
+	QuestionSet string
+	// QuestionSetIndex tells apart the reports of the unnamed question sets of a team, it is 0 for the named sets
+	QuestionSetIndex int
+	Skipped          bool
+	// OutOfOffice reports are stored for the members out of office when the report is posted
+	OutOfOffice bool
+	// Date of the report the answers are for, the next one when not set
+	Date time.Time
+	// Window the report was entered in
+	Window Window
+	// StartedAt is when the member started to answer, SubmittedAt when the report was saved
+	StartedAt   time.Time
+	SubmittedAt time.Time
+	// EditedAt is when the report was last redone with restart, replacing its PreviousVersions
+	EditedAt         time.Time
+	PreviousVersions []*Report
+	// questions as they were asked when the report was entered
+	Questions []Question
+	// question ids / answers
 	Answers map[string]string
+	// Carried are the previous answers shown when asking the questions carried from another one, by question id
+	Carried map[string]string
+}
+
+// Duration is how long the member took to fill the report
+func (r *Report) Duration() time.Duration {
+	if r.StartedAt.IsZero() || r.SubmittedAt.IsZero() {
+		return 0
+	}
+	return r.SubmittedAt.Sub(r.StartedAt)
+}
+
+// PendingReport is a report a member still has to fill before its deadline
+type PendingReport struct {
+	Team        string
+	QuestionSet *QuestionSet
+	Deadline    time.Time
+}
+
+// questionText returns the current wording of a question, or the wording it
+// had when the report was entered if it is no longer part of the question set
+func (qs *QuestionSet) questionText(q Question) string {
+	for _, current := range qs.Questions {
+		if current.ID == q.ID {
+			return current.Text
+		}
+	}
+	return q.Text
+}
+
+func emptyQuestionSetState(qs *QuestionSet, window Window) *questionSetState {
+	return &questionSetState{
+		QuestionSet:     qs,
+		enteredReports:  map[string]*Report{},
+		upcomingReports: []*Report{},
+		window:          window,
+	}
 }
 
-func emptyQuestionSetState(qs *QuestionSet) *questionSetState {
-	return &questionSetState{qs, map[string]*Report{}, false}
+func mention(userID string) string {
+	return "<@" + userID + ">"
 }
 
 func isMemberOutOfOffice(ts *TeamState, member string) bool {
@@ -68,8 +187,48 @@ func isMemberOutOfOffice(ts *TeamState, member string) bool {
 	return isOutOfOffice
 }
 
-func (ts *TeamState) postMessageToSlack(channel string, message string, params slack.PostMessageParameters) {
-	_, _, err := ts.service.slackBotAPI.PostMessage(channel, message, params)
+// sameAs tells if two question sets, possibly from different configurations, are the same
+// the sets are matched by name when they have one and by position otherwise
+func (qs *QuestionSet) sameAs(other *QuestionSet) bool {
+	if qs.Name != "" || other.Name != "" {
+		return strings.EqualFold(qs.Name, other.Name)
+	}
+	return qs.position == other.position
+}
+
+// questionSetState finds the state of a question set, the question set may come
+// from a previous configuration
+func (ts *TeamState) questionSetState(qs *QuestionSet) (*questionSetState, bool) {
+	if qsstate, ok := ts.questionSetStates[qs]; ok {
+		return qsstate, true
+	}
+	for current, qsstate := range ts.questionSetStates {
+		if current.sameAs(qs) {
+			return qsstate, true
+		}
+	}
+	return nil, false
+}
+
+// carryOver keeps the reports entered before a configuration reload
+func (ts *TeamState) carryOver(previous *TeamState) {
+	ts.paused = previous.paused
+	for qs, qsstate := range ts.questionSetStates {
+		for oldqs, oldstate := range previous.questionSetStates {
+			if oldqs.sameAs(qs) {
+				qsstate.enteredReports = oldstate.enteredReports
+				qsstate.sent = oldstate.sent
+				qsstate.upcomingReports = oldstate.upcomingReports
+				qsstate.window = oldstate.window
+				qsstate.posted = oldstate.posted
+				qsstate.previousPosted = oldstate.previousPosted
+			}
+		}
+	}
+}
+
+func (ts *TeamState) postMessageToSlack(channel string, message string, params slack.PostMessageParameters) postedMessage {
+	channelID, timestamp, err := ts.service.slackBotAPI.PostMessage(channel, message, params)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"team":    ts.Team.Name,
@@ -77,6 +236,7 @@ func (ts *TeamState) postMessageToSlack(channel string, message string, params s
 			"error":   err,
 		}).Warn("Error while posting message to slack")
 	}
+	return postedMessage{channelID, timestamp}
 }
 
 func (ts *TeamState) sendReportForTeam(qs *QuestionSet) {
@@ -85,10 +245,44 @@ func (ts *TeamState) sendReportForTeam(qs *QuestionSet) {
 		return
 	}
 	qsstate.sent = true
+	qsstate.posted = &postedReport{window: qsstate.window, sentAt: time.Now(), threads: map[string]postedMessage{}}
 
+	for _, destination := range ts.destinations(qsstate.QuestionSet) {
+		if message, ok := ts.sendReportToDestination(qsstate, destination); ok {
+			qsstate.posted.threads[destination.Channel] = message
+		}
+	}
+	for _, report := range qsstate.enteredReports {
+		ts.notifyMentions(qsstate.QuestionSet, report, qsstate.posted.threads)
+	}
+	ts.postedWebhook(qsstate)
+
+	// keeps track of the days off for the digests
+	for _, member := range ts.Members {
+		if _, ok := qsstate.enteredReports[member]; !ok && isMemberOutOfOffice(ts, member) {
+			ts.service.storeReport(&Report{
+				User:             member,
+				Team:             ts.Team.Name,
+				QuestionSet:      qsstate.Name,
+				QuestionSetIndex: qsstate.unnamedIndex,
+				Date:             qsstate.window.Date,
+				Window:           qsstate.window,
+				Skipped:          true,
+				OutOfOffice:      true,
+				Answers:          map[string]string{},
+			})
+		}
+	}
+}
+
+// sendReportToDestination posts the report to a destination and returns its first message if any
+func (ts *TeamState) sendReportToDestination(qsstate *questionSetState, destination Destination) (postedMessage, bool) {
+	isFullReport := destination.IsFullReport()
 	if len(qsstate.enteredReports) == 0 {
-		ts.postMessageToSlack(ts.Channel, "I'd like to take time to :shame: everyone for not reporting", SlackParams)
-		return
+		if isFullReport {
+			return ts.postMessageToSlack(destination.Channel, "I'd like to take time to :shame: everyone for not reporting", SlackParams), true
+		}
+		return postedMessage{}, false
 	}
 
 	attachments := []slack.Attachment{}
@@ -99,39 +293,40 @@ func (ts *TeamState) sendReportForTeam(qs *QuestionSet) {
 		report, ok := qsstate.enteredReports[member]
 		if !ok {
 			if isMemberOutOfOffice(ts, member) {
-				outOfOffice = append(outOfOffice, member)
+				outOfOffice = append(outOfOffice, ts.service.users.UserName(member))
 			} else {
-				didNotDoReport = append(didNotDoReport, member)
+				didNotDoReport = append(didNotDoReport, mention(member))
 			}
 		} else if report.Skipped {
+			if !isFullReport {
+				continue
+			}
 			attachment := slack.Attachment{
 				Color:      colorful.FastHappyColor().Hex(),
 				MarkdownIn: []string{"text", "pretext"},
-				Pretext:    "@" + member,
+				Pretext:    ts.reportPretext(report),
 				Text:       "Has nothing to declare.",
+				Footer:     ts.reportFooter(report),
 			}
 			attachments = append(attachments, attachment)
 		} else {
-			message := ""
-			for idx, q := range qsstate.QuestionSet.Questions {
-				message += q + "\n" + report.Answers[q]
-
-				if idx < len(qsstate.QuestionSet.Questions)-1 {
-					message += "\n\n"
-				}
+			message := destination.reportMessage(qsstate.QuestionSet, report)
+			if message == "" {
+				continue
 			}
 
 			attachment := slack.Attachment{
 				Color:      colorful.FastHappyColor().Hex(),
 				MarkdownIn: []string{"text", "pretext"},
-				Pretext:    "@" + member,
+				Pretext:    ts.reportPretext(report),
 				Text:       message,
+				Footer:     ts.reportFooter(report),
 			}
 			attachments = append(attachments, attachment)
 		}
 	}
 
-	if len(outOfOffice) > 0 {
+	if len(outOfOffice) > 0 && isFullReport {
 		persons := outOfOffice[0]
 		verb := "is"
 
@@ -150,133 +345,294 @@ func (ts *TeamState) sendReportForTeam(qs *QuestionSet) {
 		attachments = append(attachments, attachment)
 	}
 
+	if mood, ok := moodAttachmentText(qsstate.enteredReports); ok && isFullReport {
+		attachments = append(attachments, slack.Attachment{
+			Color:      colorful.FastHappyColor().Hex(),
+			MarkdownIn: []string{"text", "pretext"},
+			Pretext:    "Team mood",
+			Text:       mood,
+		})
+	}
+
+	if blockers := ts.service.blockers.open(ts.Team.Name); len(blockers) > 0 && destination.includesBlockers(qsstate.QuestionSet) {
+		attachments = append(attachments, ts.openBlockersAttachment(blockers, time.Now()))
+	}
+
+	if len(attachments) == 0 {
+		return postedMessage{}, false
+	}
+
+	var posted postedMessage
 	if ts.SplitReport {
-		ts.postMessageToSlack(ts.Channel, ":parrotcop: Alrighty! Here's the scrum report for today!", slack.PostMessageParameters{AsUser: true})
+		posted = ts.postMessageToSlack(destination.Channel, ":parrotcop: Alrighty! Here's the scrum report for today!", slack.PostMessageParameters{AsUser: true})
 		for i := 0; i < len(attachments); i++ {
 			params := slack.PostMessageParameters{
 				AsUser:      true,
 				Attachments: []slack.Attachment{attachments[i]},
 			}
-			ts.postMessageToSlack(ts.Channel, "*Scrum by:*", params)
+			ts.postMessageToSlack(destination.Channel, "*Scrum by:*", params)
 		}
 	} else {
 		params := slack.PostMessageParameters{
 			AsUser:      true,
 			Attachments: attachments,
 		}
-		ts.postMessageToSlack(ts.Channel, ":parrotcop: Alrighty! Here's the scrum report for today!", params)
+		posted = ts.postMessageToSlack(destination.Channel, ":parrotcop: Alrighty! Here's the scrum report for today!", params)
 	}
 
-	if len(didNotDoReport) > 0 {
-		ts.postMessageToSlack(ts.Channel, fmt.Sprintln("And lastly we should take a little time to shame", didNotDoReport), SlackParams)
+	if len(didNotDoReport) > 0 && isFullReport {
+		ts.postMessageToSlack(destination.Channel, fmt.Sprintf("And lastly we should take a little time to shame %s", strings.Join(didNotDoReport, ", ")), SlackParams)
 	}
 
 	log.WithFields(log.Fields{
 		"team":    ts.Team.Name,
-		"channel": ts.Channel,
+		"channel": destination.Channel,
 	}).Info("Sent scrum report.")
+	return posted, true
 }
 
-func (ts *TeamState) sendFirstReminder(qs *QuestionSet) {
-	qsstate := ts.questionSetStates[qs]
+func (ts *TeamState) reportPretext(report *Report) string {
+	pretext := "@" + ts.service.users.UserName(report.User)
+	if report.ReportedBy != "" {
+		pretext += " (reported by @" + ts.service.users.UserName(report.ReportedBy) + ")"
+	}
+	return pretext
+}
 
-	log.WithFields(log.Fields{
-		"team":    ts.Team.Name,
-		"channel": ts.Channel,
-	}).Info("Sending first reminder.")
+// reportFooter tells when the report was submitted, in the timezone of the member
+func (ts *TeamState) reportFooter(report *Report) string {
+	if report.SubmittedAt.IsZero() {
+		return ""
+	}
 
-	for _, member := range ts.Members {
-		if !isMemberOutOfOffice(ts, member) {
-			_, ok := qsstate.enteredReports[member]
-			if !ok {
-				_, _, err := ts.service.slackBotAPI.PostMessage("@"+member, "Hey! Don't forget to fill your report! `start` to do it or `skip` if you have nothing to say", SlackParams)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"team":    ts.Team.Name,
-						"member":  member,
-						"channel": ts.Channel,
-						"error":   err,
-					}).Warn("Could not send first reminder.")
-				}
-			}
-		} else {
-			log.WithFields(log.Fields{
-				"team":    ts.Team.Name,
-				"member":  member,
-				"channel": ts.Channel,
-			}).Info("Member out of office, not sending reminder.")
-		}
+	location := ts.service.users.UserLocation(report.User)
+	if location == nil {
+		location = ts.location
 	}
+	footer := "Submitted at " + report.SubmittedAt.In(location).Format("15:04 MST")
+	if duration := report.Duration(); duration > 0 {
+		footer += " in " + duration.Round(time.Second).String()
+	}
+	if len(report.PreviousVersions) == 1 {
+		footer += ", redone once"
+	} else if len(report.PreviousVersions) > 1 {
+		footer += fmt.Sprintf(", redone %d times", len(report.PreviousVersions))
+	}
+	return footer
 }
 
-func (ts *TeamState) sendLastReminder(qs *QuestionSet) {
-	qsstate := ts.questionSetStates[qs]
-	didNotDoReport := []string{}
+// sendLateReport posts a report entered for a report already sent as an addendum to it,
+// in the thread of the report when it is known
+func (ts *TeamState) sendLateReport(qsstate *questionSetState, report *Report) {
+	qs := qsstate.QuestionSet
+	threads := map[string]postedMessage{}
+	sent := map[string]postedMessage{}
+	for _, posted := range []*postedReport{qsstate.previousPosted, qsstate.posted} {
+		if posted != nil && posted.window.same(report.Window) {
+			threads = posted.threads
+		}
+	}
+
+	for _, destination := range ts.destinations(qs) {
+		message := "Has nothing to declare."
+		if !report.Skipped {
+			message = destination.reportMessage(qs, report)
+		} else if !destination.IsFullReport() {
+			continue
+		}
+		if message == "" {
+			continue
+		}
+
+		params := slack.PostMessageParameters{
+			AsUser: true,
+			Attachments: []slack.Attachment{{
+				Color:      colorful.FastHappyColor().Hex(),
+				MarkdownIn: []string{"text", "pretext"},
+				Pretext:    ts.reportPretext(report) + " (late)",
+				Text:       message,
+				Footer:     ts.reportFooter(report),
+			}},
+		}
+
+		if thread, ok := threads[destination.Channel]; ok && thread.timestamp != "" {
+			params.ThreadTimestamp = thread.timestamp
+			ts.postMessageToSlack(thread.channelID, ":hourglass: Late report", params)
+			sent[destination.Channel] = thread
+			continue
+		}
+		sent[destination.Channel] = ts.postMessageToSlack(destination.Channel, fmt.Sprintf(":hourglass: Late addendum to the scrum report of %s", report.Date.Format(DateFormat)), params)
+	}
+	ts.notifyMentions(qs, report, sent)
 
 	log.WithFields(log.Fields{
-		"team":    ts.Team.Name,
-		"channel": ts.Channel,
-	}).Info("Sending last reminder.")
+		"team": ts.Team.Name,
+		"user": report.User,
+		"date": report.Date.Format(DateFormat),
+	}).Info("Sent late scrum report.")
+}
 
-	for _, member := range ts.Members {
-		if !isMemberOutOfOffice(ts, member) {
-			_, ok := qsstate.enteredReports[member]
-			if !ok {
-				didNotDoReport = append(didNotDoReport, "@"+member)
-			}
+// destinations are the channels receiving the report of a question set, by
+// default the full report goes to every channel of the team
+func (ts *TeamState) destinations(qs *QuestionSet) []Destination {
+	if len(qs.Destinations) > 0 {
+		return qs.Destinations
+	}
+
+	destinations := make([]Destination, len(ts.Channels))
+	for i, channel := range ts.Channels {
+		destinations[i] = Destination{Channel: channel}
+	}
+	return destinations
+}
+
+// IsFullReport tells if the destination receives all the answers or only some questions
+func (d Destination) IsFullReport() bool {
+	return len(d.Questions) == 0
+}
+
+func (d Destination) includes(questionID string) bool {
+	if d.IsFullReport() {
+		return true
+	}
+	for _, id := range d.Questions {
+		if id == questionID {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(didNotDoReport) == 0 {
-		return
+func (d Destination) includesBlockers(qs *QuestionSet) bool {
+	for _, q := range qs.Questions {
+		if q.Blocker && d.includes(q.ID) {
+			return true
+		}
 	}
+	return d.IsFullReport()
+}
 
-	memberThatDidNotDoReport := strings.Join(didNotDoReport, ", ")
-	ts.postMessageToSlack(ts.Channel, fmt.Sprintf("Last chance to fill report! :shame: to: %s", memberThatDidNotDoReport), SlackParams)
+// reportMessage renders the answers of a report, a destination restricted to
+// some questions only gets the meaningful answers to these questions
+func (d Destination) reportMessage(qs *QuestionSet, report *Report) string {
+	answers := []string{}
+	for _, q := range report.Questions {
+		answer := report.Answers[q.ID]
+		if !d.IsFullReport() && (!d.includes(q.ID) || strings.TrimSpace(answer) == "" || (q.Blocker && !isBlockerAnswer(answer))) {
+			continue
+		}
+		text := qs.questionText(q) + "\n" + q.FormatAnswer(answer)
+		if previous, ok := report.Carried[q.ID]; ok && q.Compare {
+			text += "\n> _" + qs.questionText(Question{ID: q.CarryFrom, Text: q.CarryFrom}) + "_ " + strings.Replace(previous, "\n", "\n> ", -1)
+		}
+		answers = append(answers, text)
+	}
+	return strings.Join(answers, "\n\n")
 }
 
-type ScrumReportJob struct {
-	*TeamState
-	*QuestionSet
+// noBlockerAnswers are the answers to a blocker question that mean there is nothing to escalate
+var noBlockerAnswers = map[string]bool{
+	"":        true,
+	"-":       true,
+	"no":      true,
+	"nope":    true,
+	"non":     true,
+	"none":    true,
+	"nothing": true,
+	"n/a":     true,
+	"na":      true,
+	"nah":     true,
 }
 
-func (job *ScrumReportJob) Run() {
-	job.TeamState.sendReportForTeam(job.QuestionSet)
-	// Reset the questionSetState
-	job.TeamState.questionSetStates[job.QuestionSet] = emptyQuestionSetState(job.QuestionSet)
+func isBlockerAnswer(answer string) bool {
+	return !noBlockerAnswers[strings.Trim(strings.ToLower(answer), " .!\n")]
 }
 
-type iteration uint8
+// escalateBlockers notifies the leads and the escalation channel of the blockers
+// reported, without waiting for the scheduled report
+func (ts *TeamState) escalateBlockers(qs *QuestionSet, report *Report) {
+	if report.Skipped {
+		return
+	}
 
-const (
-	First iteration = iota
-	Last
-)
+	for _, q := range qs.Questions {
+		if !q.Blocker || !isBlockerAnswer(report.Answers[q.ID]) {
+			continue
+		}
+
+		blocker, isNew := ts.service.blockers.raise(ts.Team.Name, report.User, q.ID, report.Answers[q.ID], time.Now())
+		if !isNew {
+			continue
+		}
+
+		message := fmt.Sprintf(":rotating_light: %s reported blocker #%d in team %s\n*%s*\n%s", mention(report.User), blocker.ID, ts.Team.Name, q.Text, blocker.Text)
+		for _, lead := range ts.Leads {
+			ts.postMessageToSlack(lead, message, SlackParams)
+		}
+		if ts.EscalationChannel != "" {
+			ts.postMessageToSlack(ts.EscalationChannel, message, SlackParams)
+		}
+		ts.postMessageToSlack(report.User, fmt.Sprintf("I've registered your blocker as #%d, it will show up in every report until you tell me `resolve %d`", blocker.ID, blocker.ID), SlackParams)
+
+		log.WithFields(log.Fields{
+			"team":     ts.Team.Name,
+			"user":     report.User,
+			"question": q.ID,
+			"blocker":  blocker.ID,
+		}).Info("Blocker escalated.")
+	}
+}
+
+func (ts *TeamState) openBlockersAttachment(blockers []*Blocker, now time.Time) slack.Attachment {
+	lines := make([]string, len(blockers))
+	for i, b := range blockers {
+		lines[i] = fmt.Sprintf("#%d @%s (%s): %s", b.ID, ts.service.users.UserName(b.User), b.age(now), b.Text)
+	}
+
+	return slack.Attachment{
+		Color:      "danger",
+		MarkdownIn: []string{"text", "pretext"},
+		Pretext:    "Open blockers",
+		Text:       strings.Join(lines, "\n"),
+	}
+}
 
-type ScrumReminderJob struct {
-	iteration iteration
+type ScrumReportJob struct {
 	*TeamState
 	*QuestionSet
 }
 
-func (job *ScrumReminderJob) Run() {
-	// Post to slack things
-	if job.iteration == First {
-		job.TeamState.sendFirstReminder(job.QuestionSet)
-		return
+func (job *ScrumReportJob) Run() {
+	if !job.TeamState.paused {
+		job.TeamState.sendReportForTeam(job.QuestionSet)
 	}
-	if job.iteration == Last {
-		job.TeamState.sendLastReminder(job.QuestionSet)
-		return
+	// Reset the questionSetState
+	previous := job.TeamState.questionSetStates[job.QuestionSet]
+	qsstate := emptyQuestionSetState(job.QuestionSet, job.TeamState.window(job.QuestionSet, time.Now()))
+	qsstate.previousPosted = previous.posted
+	job.TeamState.questionSetStates[job.QuestionSet] = qsstate
+
+	// The reports entered in advance for the next report are now due
+	for _, report := range previous.upcomingReports {
+		if !report.Window.ReportAt.After(qsstate.window.ReportAt) {
+			qsstate.enteredReports[report.User] = report
+		} else {
+			qsstate.upcomingReports = append(qsstate.upcomingReports, report)
+		}
 	}
 }
 
-func NewService(configurationProvider ConfigurationProvider, slackBotAPI *slack.Client) Service {
+func NewService(configurationProvider ConfigurationProvider, slackBotAPI *slack.Client, users UserDirectory, store ReportStore) Service {
 	mod := &service{
 		configurationProvider: configurationProvider,
 		slackBotAPI:           slackBotAPI,
+		users:                 users,
 		teamStates:            map[string]*TeamState{},
 		lastEnteredReport:     map[string]*Report{},
+		restartedReports:      map[string]*Report{},
+		blockers:              newBlockerRegistry(store),
+		store:                 store,
+		webhookClient:         newWebhookClient(),
 	}
 
 	// initial *refresh
@@ -290,6 +646,12 @@ func NewService(configurationProvider ConfigurationProvider, slackBotAPI *slack.
 	return mod
 }
 
+// ReloadUsers resolves the configured users again, once the user directory knows more of them
+func (mod *service) ReloadUsers() {
+	log.Info("Users reloaded, refreshing teams.")
+	mod.refresh(mod.configurationProvider.Config())
+}
+
 func (mod *service) refresh(config *Config) {
 	teams := config.ToTeams()
 
@@ -307,7 +669,11 @@ func (mod *service) refresh(config *Config) {
 		}
 	}
 
+	mod.admins = mod.userIDs("", config.Admins)
+	mod.webhooks = config.webhooks()
 	for _, team := range teams {
+		mod.resolveUsers(team)
+
 		state, ok := mod.teamStates[team.Name]
 		if !ok {
 			log.WithFields(log.Fields{
@@ -320,12 +686,40 @@ func (mod *service) refresh(config *Config) {
 			}).Info("Refreshing team.")
 			state.Cron.Stop()
 		}
-		state = initTeamState(team, globalLocation, mod)
-		mod.teamStates[team.Name] = state
+		newState := initTeamState(team, globalLocation, config.membersSyncInterval(), mod)
+		if ok {
+			newState.carryOver(state)
+		}
+		mod.teamStates[team.Name] = newState
+	}
+}
+
+// resolveUsers identifies the configured members, leads and admins by their slack user id
+func (mod *service) resolveUsers(team *Team) {
+	team.IncludedMembers = mod.userIDs(team.Name, team.IncludedMembers)
+	team.ExcludedMembers = mod.userIDs(team.Name, team.ExcludedMembers)
+	team.Members = mergeMembers(team.IncludedMembers, nil, team.ExcludedMembers)
+	team.Leads = mod.userIDs(team.Name, team.Leads)
+	team.Admins = mod.userIDs(team.Name, team.Admins)
+}
+
+func (mod *service) userIDs(team string, users []string) []string {
+	ids := []string{}
+	for _, user := range users {
+		id, ok := mod.users.UserID(user)
+		if !ok {
+			log.WithFields(log.Fields{
+				"team": team,
+				"user": user,
+			}).Warn("Unknown slack user, ignoring it.")
+			continue
+		}
+		ids = append(ids, id)
 	}
+	return ids
 }
 
-func initTeamState(team *Team, globalLocation *time.Location, mod *service) *TeamState {
+func initTeamState(team *Team, globalLocation *time.Location, membersSyncInterval time.Duration, mod *service) *TeamState {
 	state := &TeamState{
 		Team:              team,
 		service:           mod,
@@ -336,13 +730,24 @@ func initTeamState(team *Team, globalLocation *time.Location, mod *service) *Tea
 	if team.Timezone != nil {
 		loc = team.Timezone
 	}
+	state.location = loc
 	state.Cron = cron.NewWithLocation(loc)
 
+	if state.syncsMembers() {
+		state.syncMembers()
+		state.Cron.Schedule(cron.Every(membersSyncInterval), &MembersSyncJob{state})
+	}
+
 	for _, qs := range team.QuestionsSets {
-		state.questionSetStates[qs] = emptyQuestionSetState(qs)
+		state.questionSetStates[qs] = emptyQuestionSetState(qs, state.window(qs, time.Now()))
 		state.Cron.Schedule(qs.ReportSchedule, &ScrumReportJob{state, qs})
-		state.Cron.Schedule(newScheduleDependentSchedule(qs.ReportSchedule, qs.FirstReminderBeforeReport), &ScrumReminderJob{First, state, qs})
-		state.Cron.Schedule(newScheduleDependentSchedule(qs.ReportSchedule, qs.LastReminderBeforeReport), &ScrumReminderJob{Last, state, qs})
+		for _, reminder := range qs.Reminders {
+			state.Cron.Schedule(newScheduleDependentSchedule(qs.ReportSchedule, reminder.Offset), &ScrumReminderJob{reminder, state, qs})
+		}
+	}
+
+	if team.Digest != nil {
+		state.Cron.Schedule(team.Digest.Schedule, &DigestJob{state})
 	}
 
 	state.Cron.Start()
@@ -375,11 +780,11 @@ func (s *scheduleDependentSchedule) Next(t time.Time) time.Time {
 	return s.depNext.Add(s.Duration)
 }
 
-func (m *service) GetTeamsForUser(username string) []string {
+func (m *service) GetTeamsForUser(userID string) []string {
 	teams := []string{}
 	for _, ts := range m.teamStates {
 		for _, member := range ts.Members {
-			if username == member {
+			if userID == member {
 				teams = append(teams, ts.Name)
 			}
 		}
@@ -390,25 +795,244 @@ func (m *service) GetTeamsForUser(username string) []string {
 
 func (m *service) GetTeamByName(teamName string) (*TeamState, error) {
 	for _, ts := range m.teamStates {
-		if teamName == ts.Team.Name {
+		if strings.EqualFold(teamName, ts.Team.Name) {
 			return ts, nil
 		}
 	}
 	return nil, errors.New("Team " + teamName + " does not exist")
 }
 
+// Location is the timezone of the team reports
+func (ts *TeamState) Location() *time.Location {
+	return ts.location
+}
+
 func (m *service) GetQuestionSetsForTeam(team string) []*QuestionSet {
 	return m.teamStates[team].QuestionsSets
 }
 
-func (m *service) SaveReport(report *Report, qs *QuestionSet) {
+// OnOpenReport registers a handler starting the questionnaire of a question set with a
+// member, it is called by the reminders configured to open the report
+func (m *service) OnOpenReport(handler func(userID string, team string, qs *QuestionSet)) {
+	m.openReportHandlers = append(m.openReportHandlers, handler)
+}
+
+// GetPendingReports lists the reports the member hasn't filled yet in the teams
+// they are in office for, the closest deadline first
+func (m *service) GetPendingReports(userID string) []PendingReport {
+	now := time.Now()
+	pending := []PendingReport{}
+	for _, ts := range m.teamStates {
+		if ts.paused || isMemberOutOfOffice(ts, userID) || !contains(ts.Members, userID) {
+			continue
+		}
+		for qs, qsstate := range ts.questionSetStates {
+			if _, ok := qsstate.enteredReports[userID]; ok {
+				continue
+			}
+			pending = append(pending, PendingReport{
+				Team:        ts.Name,
+				QuestionSet: qs,
+				Deadline:    qs.ReportSchedule.Next(now.In(ts.location)),
+			})
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Deadline.Before(pending[j].Deadline) })
+	return pending
+}
+
+// GetReportWindow is the window a report for the date entered now goes in, the
+// report of the next window when the date is not set. It fails when the window is closed.
+func (m *service) GetReportWindow(team string, qs *QuestionSet, date time.Time) (Window, error) {
+	ts, ok := m.teamStates[team]
+	if !ok {
+		return Window{}, errors.New("Team " + team + " does not exist anymore")
+	}
+	qsstate, ok := ts.questionSetState(qs)
+	if !ok {
+		return Window{}, errors.New("This set of questions does not exist anymore")
+	}
+
+	return ts.openWindow(qsstate, date, time.Now())
+}
+
+func (m *service) SaveReport(report *Report, qs *QuestionSet) error {
+	ts, ok := m.teamStates[report.Team]
+	if !ok {
+		log.WithFields(log.Fields{
+			"team": report.Team,
+			"user": report.User,
+		}).Warn("Cannot save report, team does not exist anymore.")
+		return errors.New("Team " + report.Team + " does not exist anymore")
+	}
+	qsstate, ok := ts.questionSetState(qs)
+	if !ok {
+		log.WithFields(log.Fields{
+			"team": report.Team,
+			"user": report.User,
+		}).Warn("Cannot save report, question set does not exist anymore.")
+		return errors.New("This set of questions does not exist anymore")
+	}
+
+	now := time.Now()
+	window, err := ts.openWindow(qsstate, report.Date, now)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"team":  report.Team,
+			"user":  report.User,
+			"error": err,
+		}).Info("Report rejected, its window is not open.")
+		return err
+	}
+	report.Date = window.Date
+	report.Window = window
+	report.QuestionSet = qsstate.Name
+	report.QuestionSetIndex = qsstate.unnamedIndex
+	report.SubmittedAt = now
+	m.keepPreviousVersions(report)
+	m.storeReport(report)
+	ts.reportWebhook(report)
+
 	m.lastEnteredReport[report.User] = report
-	m.teamStates[report.Team].questionSetStates[qs].enteredReports[report.User] = report
+	ts.escalateBlockers(qsstate.QuestionSet, report)
+
+	// windows rather than dates, the question sets reporting more than once a day have several windows a day
+	switch {
+	case report.Window.ReportAt.Before(qsstate.window.ReportAt):
+		ts.sendLateReport(qsstate, report)
+		return nil
+	case report.Window.ReportAt.After(qsstate.window.ReportAt):
+		qsstate.upcomingReports = append(qsstate.upcomingReports, report)
+		return nil
+	}
 
-	// if done launch report answers
-	if len(m.teamStates[report.Team].Members) == len(m.teamStates[report.Team].questionSetStates[qs].enteredReports) {
-		m.teamStates[report.Team].sendReportForTeam(qs)
+	if qsstate.sent {
+		ts.sendLateReport(qsstate, report)
+		return nil
 	}
+	qsstate.enteredReports[report.User] = report
+
+	// if done launch report answers, a paused team posts nothing
+	if !ts.paused && ts.isComplete(qsstate) {
+		ts.sendReportForTeam(qsstate.QuestionSet)
+	}
+	return nil
+}
+
+func (m *service) ResolveBlocker(userID string, id int) (*Blocker, error) {
+	blocker, err := m.blockers.resolve(id, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"team":    blocker.Team,
+		"user":    userID,
+		"blocker": blocker.ID,
+	}).Info("Blocker resolved.")
+	return blocker, nil
+}
+
+// GetReports lists the stored reports matching the filter, the most recent first
+func (m *service) GetReports(filter ReportFilter) ([]*Report, error) {
+	return m.store.Reports(filter)
+}
+
+// GetStats compiles the participation of the current members of a team since a date
+func (m *service) GetStats(team string, since time.Time) (*TeamStats, error) {
+	ts, err := m.GetTeamByName(team)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := m.store.Reports(ReportFilter{Teams: []string{ts.Name}, From: since})
+	if err != nil {
+		return nil, err
+	}
+	return computeStats(ts.Name, ts.Members, since, reports), nil
+}
+
+// GetCarriedAnswer is the answer the member gave to the question the question is carried
+// from in their previous report of the question set, before the window of the report
+func (m *service) GetCarriedAnswer(report *Report, qs *QuestionSet, question Question) (string, bool) {
+	if question.CarryFrom == "" {
+		return "", false
+	}
+
+	before := report.Window.Date
+	if before.IsZero() {
+		before = time.Now()
+	}
+	reports, err := m.store.Reports(ReportFilter{Teams: []string{report.Team}, User: report.User, To: before.AddDate(0, 0, -1)})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"team":  report.Team,
+			"user":  report.User,
+			"error": err,
+		}).Warn("Cannot load the previous report.")
+		return "", false
+	}
+
+	for _, previous := range reports {
+		if previous.questionSetKey() != qs.key() || previous.Skipped || previous.OutOfOffice {
+			continue
+		}
+		answer := strings.TrimSpace(previous.Answers[question.CarryFrom])
+		return answer, answer != ""
+	}
+	return "", false
+}
+
+// GetMoodTrend averages the mood answers of a team by week, for the last weeks
+func (m *service) GetMoodTrend(team string, weeks int) ([]MoodWeek, error) {
+	ts, err := m.GetTeamByName(team)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(ts.location)
+	reports, err := m.store.Reports(ReportFilter{Teams: []string{ts.Name}, From: now.AddDate(0, 0, -7*weeks)})
+	if err != nil {
+		return nil, err
+	}
+	return moodTrend(reports, weeks, now), nil
+}
+
+func (m *service) storeReport(report *Report) {
+	if err := m.store.Save(report); err != nil {
+		log.WithFields(log.Fields{
+			"team":  report.Team,
+			"user":  report.User,
+			"error": err,
+		}).Error("Cannot store report.")
+	}
+}
+
+func (m *service) unstoreReport(report *Report) {
+	if err := m.store.Delete(report); err != nil {
+		log.WithFields(log.Fields{
+			"team":  report.Team,
+			"user":  report.User,
+			"error": err,
+		}).Error("Cannot delete stored report.")
+	}
+}
+
+// keepPreviousVersions attaches the report deleted by restart to the report redone in its place
+func (m *service) keepPreviousVersions(report *Report) {
+	previous, ok := m.restartedReports[report.User]
+	if !ok {
+		return
+	}
+	delete(m.restartedReports, report.User)
+	if previous.Team != report.Team || windowKey(previous) != windowKey(report) {
+		return
+	}
+
+	report.PreviousVersions = append(previous.PreviousVersions, previous)
+	previous.PreviousVersions = nil
+	report.EditedAt = report.SubmittedAt
 }
 
 func (m *service) DeleteLastReport(user string) bool {
@@ -428,23 +1052,72 @@ func (m *service) DeleteLastReport(user string) bool {
 		report, ok := qs.enteredReports[r.User]
 		if ok && r == report {
 			delete(qs.enteredReports, r.User)
+			m.restartedReports[user] = r
+			m.unstoreReport(r)
 			return true
 		}
+		for i, report := range qs.upcomingReports {
+			if r == report {
+				qs.upcomingReports = append(qs.upcomingReports[:i], qs.upcomingReports[i+1:]...)
+				m.restartedReports[user] = r
+				m.unstoreReport(r)
+				return true
+			}
+		}
 	}
 
 	return false
 }
 
-func (m *service) AddToOutOfOffice(team string, username string) {
-	m.teamStates[team].OutOfOffice = append(m.teamStates[team].OutOfOffice, username)
+func (m *service) AddToOutOfOffice(team string, userID string) {
+	ts := m.teamStates[team]
+	ts.OutOfOffice = append(ts.OutOfOffice, userID)
+
+	// the report may have been waiting for this member only
+	for qs, qsstate := range ts.questionSetStates {
+		if !ts.paused && !qsstate.sent && len(qsstate.enteredReports) > 0 && ts.isComplete(qsstate) {
+			ts.sendReportForTeam(qs)
+		}
+	}
 }
 
-func (m *service) RemoveFromOutOfOffice(team string, username string) {
+func (m *service) RemoveFromOutOfOffice(team string, userID string) {
 	var ooof []string
 	for _, outOfOfficeMember := range m.teamStates[team].OutOfOffice {
-		if outOfOfficeMember != username {
+		if outOfOfficeMember != userID {
 			ooof = append(ooof, outOfOfficeMember)
 		}
 	}
 	m.teamStates[team].OutOfOffice = ooof
 }
+
+// ForceReport posts the reports of a team right away, the reports entered afterwards are late
+// addenda to them and the scheduled job moves on to the next report. A paused team posts nothing.
+func (m *service) ForceReport(team string) error {
+	ts, err := m.GetTeamByName(team)
+	if err != nil {
+		return err
+	}
+	if ts.paused {
+		return errors.New("Team " + ts.Team.Name + " is paused, resume it before forcing its report")
+	}
+
+	for _, qs := range ts.QuestionsSets {
+		ts.sendReportForTeam(qs)
+	}
+	return nil
+}
+
+func (m *service) SetPaused(team string, paused bool) error {
+	ts, err := m.GetTeamByName(team)
+	if err != nil {
+		return err
+	}
+
+	ts.paused = paused
+	log.WithFields(log.Fields{
+		"team":   team,
+		"paused": paused,
+	}).Info("Team pause changed.")
+	return nil
+}
@@ -0,0 +1,441 @@
+package scrum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/robfig/cron"
+)
+
+// slackServer answers the slack api calls and counts the posted messages
+type slackServer struct {
+	*httptest.Server
+	sync.Mutex
+	posted int
+	api    string
+}
+
+func newSlackServer() *slackServer {
+	s := &slackServer{api: slack.SLACK_API}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chat.postMessage") {
+			s.Lock()
+			s.posted++
+			s.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1.1"}`))
+	}))
+	slack.SLACK_API = s.URL + "/"
+	return s
+}
+
+func (s *slackServer) Close() {
+	slack.SLACK_API = s.api
+	s.Server.Close()
+}
+
+func (s *slackServer) postedMessages() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.posted
+}
+
+// reportingTeam is a team of two members where U1 reported, its report is posted to the general channel
+func reportingTeam(t *testing.T) (*service, *TeamState, *QuestionSet) {
+	schedule, _ := cron.Parse("0 0 9 * * *")
+	store, _ := NewFileReportStore("")
+	qs := &QuestionSet{ReportSchedule: schedule, Questions: []Question{{ID: "today", Text: "Today?"}}}
+	m := &service{
+		slackBotAPI:       slack.New("xoxb"),
+		users:             namesDirectory{},
+		store:             store,
+		blockers:          newBlockerRegistry(nil),
+		teamStates:        map[string]*TeamState{},
+		lastEnteredReport: map[string]*Report{},
+		restartedReports:  map[string]*Report{},
+	}
+	ts := &TeamState{
+		Team:              &Team{Name: "L337", Channels: []string{"general"}, Members: []string{"U1", "U2"}, QuestionsSets: []*QuestionSet{qs}},
+		service:           m,
+		questionSetStates: map[*QuestionSet]*questionSetState{},
+		location:          time.UTC,
+	}
+	ts.questionSetStates[qs] = emptyQuestionSetState(qs, ts.window(qs, time.Now()))
+	ts.questionSetStates[qs].enteredReports["U1"] = &Report{User: "U1", Team: "L337", Questions: qs.Questions, Answers: map[string]string{"today": "Ship it"}}
+	m.teamStates["L337"] = ts
+	return m, ts, qs
+}
+
+func TestBlockerAnswers(t *testing.T) {
+	for _, answer := range []string{"yes", "Waiting on @alice for a review", "Yes!"} {
+		if !isBlockerAnswer(answer) {
+			t.Error("expected a blocker for", answer)
+		}
+	}
+
+	for _, answer := range []string{"", " ", "no", "No.", "nope", "None", "n/a", "-"} {
+		if isBlockerAnswer(answer) {
+			t.Error("expected no blocker for", answer)
+		}
+	}
+}
+
+func TestBlockersStayOpenUntilResolvedByTheirOwner(t *testing.T) {
+	registry := newBlockerRegistry(nil)
+	raisedAt := time.Date(2018, 3, 5, 9, 0, 0, 0, time.UTC)
+
+	blocker, isNew := registry.raise("L337", "pa", "blockers", "Waiting on a review", raisedAt)
+	if !isNew {
+		t.Fail()
+	}
+	if _, isNew := registry.raise("L337", "pa", "blockers", "waiting on a review ", raisedAt.Add(24*time.Hour)); isNew {
+		t.Error("the same blocker reported again should not be duplicated")
+	}
+
+	if blocker.AgeInDays(raisedAt.Add(50*time.Hour)) != 2 {
+		t.Fail()
+	}
+
+	if _, err := registry.resolve(blocker.ID, "jo", raisedAt); err == nil {
+		t.Error("only the owner can resolve a blocker")
+	}
+	if len(registry.open("L337")) != 1 {
+		t.Fail()
+	}
+
+	if _, err := registry.resolve(blocker.ID, "pa", raisedAt); err != nil {
+		t.Error(err)
+	}
+	if len(registry.open("L337")) != 0 {
+		t.Fail()
+	}
+}
+
+func TestDestinationRestrictedToQuestionsOnlyGetsMeaningfulAnswers(t *testing.T) {
+	qs := &QuestionSet{Questions: []Question{
+		{ID: "today", Text: "What will you do today?"},
+		{ID: "blockers", Text: "Are you blocked?", Blocker: true},
+	}}
+	blocked := &Report{Questions: qs.Questions, Answers: map[string]string{"today": "Code", "blockers": "Waiting on a review"}}
+	notBlocked := &Report{Questions: qs.Questions, Answers: map[string]string{"today": "Code", "blockers": "no"}}
+
+	leads := Destination{Channel: "leads", Questions: []string{"blockers"}}
+	if leads.reportMessage(qs, blocked) != "Are you blocked?\nWaiting on a review" {
+		t.Error("unexpected message", leads.reportMessage(qs, blocked))
+	}
+	if leads.reportMessage(qs, notBlocked) != "" {
+		t.Error("unexpected message", leads.reportMessage(qs, notBlocked))
+	}
+
+	team := Destination{Channel: "general"}
+	if team.reportMessage(qs, notBlocked) != "What will you do today?\nCode\n\nAre you blocked?\nno" {
+		t.Error("unexpected message", team.reportMessage(qs, notBlocked))
+	}
+}
+
+func TestMergeMembersKeepsIncludedAndDropsExcluded(t *testing.T) {
+	members := mergeMembers([]string{"U0PA", "U0JO"}, []string{"U0FBOUTIN2", "U0JO", "U0LBOURDAGES", "U0PA"}, []string{"U0LBOURDAGES"})
+
+	if strings.Join(members, ",") != "U0PA,U0JO,U0FBOUTIN2" {
+		t.Error("unexpected members", members)
+	}
+}
+
+func TestRolesFromTeamAndGlobalConfiguration(t *testing.T) {
+	m := &service{
+		admins: []string{"U0BOSS"},
+		teamStates: map[string]*TeamState{
+			"L337": {Team: &Team{Name: "L337", Leads: []string{"U0PA"}, Admins: []string{"U0JO"}}},
+		},
+	}
+
+	if m.GetRole("L337", "U0BOSS") != AdminRole || m.GetRole("L337", "U0JO") != AdminRole {
+		t.Error("admins should be admins")
+	}
+	if m.GetRole("L337", "U0PA") != LeadRole {
+		t.Error("leads should be leads")
+	}
+	if m.GetRole("L337", "U0FBOUTIN2") != MemberRole || m.GetRole("Other", "U0PA") != MemberRole {
+		t.Error("everyone else is a member")
+	}
+}
+
+func TestWindowDateIsTheDateOfTheNextReport(t *testing.T) {
+	schedule, _ := cron.Parse("0 5 9 * * 1-5")
+	ts := &TeamState{location: time.UTC}
+	qs := &QuestionSet{ReportSchedule: schedule}
+
+	// friday after the report, the next one is on monday
+	if date := ts.window(qs, time.Date(2018, 3, 9, 10, 0, 0, 0, time.UTC)).Date; date.Format(DateFormat) != "2018-03-12" {
+		t.Error("unexpected window date", date)
+	}
+	// monday before the report
+	if date := ts.window(qs, time.Date(2018, 3, 12, 8, 0, 0, 0, time.UTC)).Date; date.Format(DateFormat) != "2018-03-12" {
+		t.Error("unexpected window date", date)
+	}
+}
+
+func TestReportsGoInTheOpenWindowOfTheirDate(t *testing.T) {
+	schedule, _ := cron.Parse("0 */30 * * * *")
+	ts := &TeamState{location: time.UTC}
+	qs := &QuestionSet{ReportSchedule: schedule, LateGracePeriod: 10 * time.Minute}
+	previous := ts.window(qs, time.Date(2018, 3, 9, 9, 45, 0, 0, time.UTC))
+	qsstate := emptyQuestionSetState(qs, ts.window(qs, time.Date(2018, 3, 9, 10, 0, 0, 0, time.UTC)))
+	qsstate.previousPosted = &postedReport{window: previous}
+	today := time.Date(2018, 3, 9, 0, 0, 0, 0, time.UTC)
+
+	// during the grace period of the report of 10:00, same date as the one of 10:30
+	late := time.Date(2018, 3, 9, 10, 5, 0, 0, time.UTC)
+	for _, date := range []time.Time{{}, today} {
+		if window := ts.reportWindow(qsstate, date, late); !window.same(previous) {
+			t.Error("the report is late for the report of 10:00", window.ReportAt)
+		}
+	}
+
+	after := time.Date(2018, 3, 9, 10, 20, 0, 0, time.UTC)
+	if window := ts.reportWindow(qsstate, today, after); !window.same(qsstate.window) {
+		t.Error("the report is for the report of 10:30", window.ReportAt)
+	}
+}
+
+func TestCompletionPolicies(t *testing.T) {
+	ts := &TeamState{Team: &Team{Members: []string{"U1", "U2", "U3", "U4"}, OutOfOffice: []string{"U4"}}}
+	qsstate := emptyQuestionSetState(&QuestionSet{}, Window{})
+	qsstate.enteredReports["U1"] = &Report{User: "U1"}
+	qsstate.enteredReports["U2"] = &Report{User: "U2"}
+
+	for policy, expected := range map[CompletionPolicy]bool{
+		CompleteWhenAllReported:      false,
+		CompleteWhenInOfficeReported: false,
+		CompleteOnQuorum:             true,
+		CompleteOnSchedule:           false,
+	} {
+		qsstate.Completion, qsstate.Quorum = policy, 60
+		if ts.isComplete(qsstate) != expected {
+			t.Error("unexpected completion for", policy, "with 2 of 3 members in office")
+		}
+	}
+
+	qsstate.enteredReports["U3"] = &Report{User: "U3"}
+	qsstate.Completion = CompleteWhenInOfficeReported
+	if !ts.isComplete(qsstate) {
+		t.Error("every member in office reported")
+	}
+	qsstate.Completion = CompleteWhenAllReported
+	if ts.isComplete(qsstate) {
+		t.Error("the out of office member didn't report")
+	}
+}
+
+func TestConfiguredReportWindowRejectsReportsOutsideOfIt(t *testing.T) {
+	schedule, _ := cron.Parse("0 0 9 * * 1-5")
+	ts := &TeamState{location: time.UTC}
+	qs := &QuestionSet{ReportSchedule: schedule, ReportWindow: &ReportWindow{Opens: -3 * time.Hour, Closes: time.Hour}}
+	qsstate := emptyQuestionSetState(qs, ts.window(qs, time.Date(2018, 3, 9, 1, 0, 0, 0, time.UTC)))
+
+	if _, err := ts.openWindow(qsstate, time.Time{}, time.Date(2018, 3, 9, 5, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("the window opens at 6")
+	}
+	if window, err := ts.openWindow(qsstate, time.Time{}, time.Date(2018, 3, 9, 8, 0, 0, 0, time.UTC)); err != nil || window.Date.Format(DateFormat) != "2018-03-09" {
+		t.Error("the window is open", window, err)
+	}
+	if _, err := ts.openWindow(qsstate, time.Date(2018, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 9, 8, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("the window of the day before closed")
+	}
+	if window, err := ts.openWindow(qsstate, time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 9, 8, 0, 0, 0, time.UTC)); err != nil || window.Date.Format(DateFormat) != "2018-03-12" {
+		t.Error("the reports of a future date wait for their window", window, err)
+	}
+
+	// the grace period follows the configured window
+	qs.LateGracePeriod = 30 * time.Minute
+	qsstate = emptyQuestionSetState(qs, ts.window(qs, time.Date(2018, 3, 9, 1, 0, 0, 0, time.UTC)))
+	today := time.Date(2018, 3, 9, 0, 0, 0, 0, time.UTC)
+	if _, err := ts.openWindow(qsstate, today, time.Date(2018, 3, 9, 10, 15, 0, 0, time.UTC)); err != nil {
+		t.Error("the report is in the grace period", err)
+	}
+	if _, err := ts.openWindow(qsstate, today, time.Date(2018, 3, 9, 10, 45, 0, 0, time.UTC)); err == nil {
+		t.Error("the grace period is over")
+	}
+
+	// without a configured window, reports are always accepted
+	qs.ReportWindow = nil
+	if _, err := ts.openWindow(qsstate, time.Date(2018, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 9, 8, 0, 0, 0, time.UTC)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRestartedReportIsAPreviousVersion(t *testing.T) {
+	m := &service{restartedReports: map[string]*Report{}}
+	date := time.Date(2018, 3, 9, 0, 0, 0, 0, time.UTC)
+	first := &Report{User: "U1", Team: "L337", Date: date}
+	m.restartedReports["U1"] = first
+
+	redone := &Report{User: "U1", Team: "L337", Date: date, SubmittedAt: date.Add(9 * time.Hour)}
+	m.keepPreviousVersions(redone)
+	if len(redone.PreviousVersions) != 1 || redone.PreviousVersions[0] != first || !redone.EditedAt.Equal(redone.SubmittedAt) {
+		t.Error("the restarted report is not kept", redone.PreviousVersions)
+	}
+
+	// a report of another day is not a new version
+	m.restartedReports["U1"] = redone
+	other := &Report{User: "U1", Team: "L337", Date: date.AddDate(0, 0, 1)}
+	m.keepPreviousVersions(other)
+	if len(other.PreviousVersions) != 0 || len(m.restartedReports) != 0 {
+		t.Fail()
+	}
+}
+
+func TestMoodTrendByWeek(t *testing.T) {
+	questions := []Question{{ID: "mood", Type: MoodQuestion}}
+	now := time.Date(2018, 3, 16, 10, 0, 0, 0, time.UTC)
+	reports := []*Report{
+		{Date: now, Questions: questions, Answers: map[string]string{"mood": "5"}},
+		{Date: now.AddDate(0, 0, -1), Questions: questions, Answers: map[string]string{"mood": "4"}},
+		{Date: now.AddDate(0, 0, -14), Questions: questions, Answers: map[string]string{"mood": "1"}},
+		{Date: now.AddDate(0, 0, -14), Questions: questions, Answers: map[string]string{"mood": ":neutral_face:"}},
+	}
+
+	trend := moodTrend(reports, 3, now)
+	if trend[2].Average != 4.5 || trend[2].Answers != 2 || trend[1].Answers != 0 || trend[0].Average != 2 {
+		t.Error("unexpected trend", trend)
+	}
+	if line := Sparkline(trend); line != "▂·▇" {
+		t.Error("unexpected sparkline", line)
+	}
+
+	if _, ok := ParseMood("6"); ok {
+		t.Error("the mood scale goes up to 5")
+	}
+}
+
+func TestMoodTrendWeeksAcrossDaylightSavingTime(t *testing.T) {
+	location, err := time.LoadLocation("America/Montreal")
+	if err != nil {
+		t.Skip("no time zone database", err)
+	}
+	questions := []Question{{ID: "mood", Type: MoodQuestion}}
+	// the clocks moved forward on 2018-03-11, in the first week
+	now := time.Date(2018, 3, 23, 10, 0, 0, 0, location)
+	reports := []*Report{
+		{Date: time.Date(2018, 3, 17, 0, 0, 0, 0, location), Questions: questions, Answers: map[string]string{"mood": "5"}},
+		{Date: time.Date(2018, 3, 16, 0, 0, 0, 0, location), Questions: questions, Answers: map[string]string{"mood": "1"}},
+	}
+
+	trend := moodTrend(reports, 2, now)
+	if !trend[1].Start.Equal(time.Date(2018, 3, 17, 0, 0, 0, 0, location)) || trend[1].Average != 5 || trend[0].Average != 1 {
+		t.Error("unexpected trend", trend)
+	}
+}
+
+func TestMentionedUsersInAnswers(t *testing.T) {
+	report := &Report{
+		User:      "U1",
+		Questions: []Question{{ID: "today"}, {ID: "blockers"}},
+		Answers: map[string]string{
+			"today":    "Pairing with <@U2> and <@U1>",
+			"blockers": "Blocked by <@U3|alice> for review, and <@U2> again",
+		},
+	}
+
+	users := mentionedUsers(report)
+	if len(users) != 2 || users[0] != "U2" || users[1] != "U3" {
+		t.Error("unexpected mentioned users", users)
+	}
+
+	if !mentions(report.Answers["blockers"], "U3") || mentions("Pairing with <@U23>", "U2") {
+		t.Error("mentions are matched by prefix")
+	}
+}
+
+func TestCarriedAnswerIsFromThePreviousReport(t *testing.T) {
+	store, _ := NewFileReportStore("")
+	m := &service{store: store}
+	date := time.Date(2018, 3, 9, 0, 0, 0, 0, time.UTC)
+	store.Save(&Report{User: "U1", Team: "L337", Date: date.AddDate(0, 0, -2), Answers: map[string]string{"today": "Write the tests"}})
+	store.Save(&Report{User: "U1", Team: "L337", Date: date.AddDate(0, 0, -1), Skipped: true, Answers: map[string]string{}})
+	store.Save(&Report{User: "U1", Team: "L337", QuestionSet: "retro", Date: date.AddDate(0, 0, -1), Answers: map[string]string{"today": "Retro"}})
+	store.Save(&Report{User: "U1", Team: "L337", Date: date, Answers: map[string]string{"today": "Ship it"}})
+
+	question := Question{ID: "yesterday", CarryFrom: "today", Compare: true}
+	report := &Report{User: "U1", Team: "L337", Window: Window{Date: date}, Questions: []Question{question}, Answers: map[string]string{"yesterday": "Wrote the tests"}}
+	qs := &QuestionSet{Questions: []Question{{ID: "today", Text: "What will you do today?"}, {ID: "yesterday", Text: "What did you do yesterday?"}}}
+	previous, ok := m.GetCarriedAnswer(report, qs, question)
+	if !ok || previous != "Write the tests" {
+		t.Error("unexpected carried answer", previous)
+	}
+
+	report.Carried = map[string]string{"yesterday": previous}
+	if message := (Destination{}).reportMessage(qs, report); message != "What did you do yesterday?\nWrote the tests\n> _What will you do today?_ Write the tests" {
+		t.Error("unexpected report message", message)
+	}
+
+	if _, ok := m.GetCarriedAnswer(report, qs, Question{ID: "today"}); ok {
+		t.Error("the question is not carried")
+	}
+
+	if _, ok := m.GetCarriedAnswer(report, &QuestionSet{unnamedIndex: 1}, question); ok {
+		t.Error("the answer is carried from another unnamed question set")
+	}
+}
+
+func TestForcedReportIsNotPostedAgainOnSchedule(t *testing.T) {
+	server := newSlackServer()
+	defer server.Close()
+	m, ts, qs := reportingTeam(t)
+
+	if err := m.ForceReport("L337"); err != nil {
+		t.Fatal(err)
+	}
+	posted := server.postedMessages()
+	if posted == 0 {
+		t.Fatal("the forced report was not posted")
+	}
+
+	(&ScrumReportJob{ts, qs}).Run()
+	if server.postedMessages() != posted {
+		t.Error("the report of the window was posted again")
+	}
+}
+
+func TestPausedTeamDoesNotPostCompleteReports(t *testing.T) {
+	server := newSlackServer()
+	defer server.Close()
+	m, ts, qs := reportingTeam(t)
+	ts.paused = true
+
+	m.AddToOutOfOffice("L337", "U2")
+	if err := m.SaveReport(&Report{User: "U1", Team: "L337", Questions: qs.Questions, Answers: map[string]string{"today": "Ship it again"}}, qs); err != nil {
+		t.Fatal(err)
+	}
+	if server.postedMessages() != 0 || ts.questionSetStates[qs].sent {
+		t.Error("the report of a paused team was posted")
+	}
+}
+
+func TestPausedTeamReportCannotBeForced(t *testing.T) {
+	schedule, _ := cron.Parse("0 0 9 * * 1-5")
+	qs := &QuestionSet{ReportSchedule: schedule}
+	report := &Report{User: "U1", Team: "L337", Answers: map[string]string{}}
+	qsstate := emptyQuestionSetState(qs, Window{})
+	qsstate.enteredReports["U1"] = report
+	ts := &TeamState{
+		Team:              &Team{Name: "L337", QuestionsSets: []*QuestionSet{qs}},
+		questionSetStates: map[*QuestionSet]*questionSetState{qs: qsstate},
+		paused:            true,
+		location:          time.UTC,
+	}
+	m := &service{teamStates: map[string]*TeamState{"L337": ts}}
+
+	if err := m.ForceReport("L337"); err == nil {
+		t.Error("a paused team report was forced")
+	}
+	if ts.questionSetStates[qs].enteredReports["U1"] != report {
+		t.Error("the entered reports were dropped")
+	}
+}
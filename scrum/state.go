@@ -1,6 +1,7 @@
 package scrum
 
 import (
+	"text/template"
 	"time"
 
 	"github.com/robfig/cron"
@@ -9,18 +10,105 @@ import (
 type (
 	Team struct {
 		Name          string
-		Channel       string
+		Channels      []string
 		Members       []string
 		QuestionsSets []*QuestionSet
-		Timezone      *time.Location
-		OutOfOffice   []string
-		SplitReport   bool
+
+		// Members are synchronized from this slack user group and/or channel when set,
+		// IncludedMembers are always members and ExcludedMembers never are
+		MembersFromUserGroup string
+		MembersFromChannel   string
+		IncludedMembers      []string
+		ExcludedMembers      []string
+
+		Timezone    *time.Location
+		OutOfOffice []string
+		SplitReport bool
+
+		// Leads and EscalationChannel are notified as soon as a member reports a blocker
+		Leads             []string
+		EscalationChannel string
+		// Admins can force the reports and pause the team
+		Admins []string
+
+		// Digest compiles the reports of the week, when set
+		Digest *Digest
+		// Webhooks receive the events of the team, along with the global ones
+		Webhooks []Webhook
+	}
+
+	// Webhook receives the events of the reports as json payloads
+	Webhook struct {
+		URL string
+		// Secret signs the payloads, the signature is in the WebhookSignatureHeader
+		Secret string
+		// Events received, all of them when empty
+		Events []string
+	}
+
+	// Digest is posted on its schedule to the team channels, or to the leads for the lead audience
+	Digest struct {
+		Schedule cron.Schedule
+		Audience Audience
 	}
 
 	QuestionSet struct {
-		Questions                 []string
-		ReportSchedule            cron.Schedule
-		FirstReminderBeforeReport time.Duration
-		LastReminderBeforeReport  time.Duration
+		Name           string
+		Description    string
+		Questions      []Question
+		ReportSchedule cron.Schedule
+		// Reminders are sent at their offset from each report
+		Reminders []Reminder
+		// Destinations override the team channels for the report of this question set
+		Destinations []Destination
+		// Completion tells when the report is posted before its scheduled time, Quorum is
+		// the percentage of the members in office needed by the quorum policy
+		Completion CompletionPolicy
+		Quorum     int
+		// ReportWindow restricts when the reports are accepted, they are accepted anytime when not set
+		ReportWindow *ReportWindow
+		// LateGracePeriod is how long after a report is posted the reports entered are added to it
+		LateGracePeriod time.Duration
+
+		// position of the question set in the team configuration, used to find unnamed sets back after a reload
+		position int
+		// unnamedIndex is the rank of an unnamed question set among the unnamed sets of the team
+		unnamedIndex int
+	}
+
+	// Reminder is sent Offset from the report time to the members who haven't reported yet,
+	// to the channels or to the leads depending on its Audience
+	Reminder struct {
+		Offset   time.Duration
+		Audience Audience
+		Template *template.Template
+		// OpenReport starts the questionnaire with the reminded member, their next message answers the first question
+		OpenReport bool
+	}
+
+	// ReportWindow opens and closes at offsets from the report time
+	ReportWindow struct {
+		Opens  time.Duration
+		Closes time.Duration
+	}
+
+	// Destination is a channel receiving a report, restricted to some question ids if any
+	Destination struct {
+		Channel   string
+		Questions []string
+	}
+
+	// Question is identified by an ID that stays the same when its text is reworded
+	Question struct {
+		ID   string
+		Text string
+		// Blocker questions are escalated as soon as they are answered
+		Blocker bool
+		// Type is MoodQuestion for the questions answered on a scale from 1 to 5, free text otherwise
+		Type string
+		// CarryFrom is the id of the question whose previous answer is shown when asking this one,
+		// Compare shows that previous answer next to the new one in the report
+		CarryFrom string
+		Compare   bool
 	}
 )
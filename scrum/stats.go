@@ -0,0 +1,119 @@
+package scrum
+
+import (
+	"sort"
+	"time"
+)
+
+// Stats is the participation of a member, or of the whole team
+type Stats struct {
+	// Expected is the number of reports expected, the ones of the days out of office are not
+	Expected int `json:"expected"`
+	// Reported counts the reports filled, skipped ones included
+	Reported int `json:"reported"`
+	Skipped  int `json:"skipped"`
+	// CompletionRate is the part of the expected reports that were filled, SkipRate the part of them that were skipped
+	CompletionRate float64 `json:"completion_rate"`
+	SkipRate       float64 `json:"skip_rate"`
+	// AverageBeforeDeadline is how long before the report the reports are submitted, negative when they are late
+	AverageBeforeDeadline time.Duration `json:"-"`
+	AverageSecondsEarly   float64       `json:"average_seconds_before_deadline"`
+
+	early   time.Duration
+	samples int
+}
+
+// MemberStats is the participation of a member of a team
+type MemberStats struct {
+	User string `json:"user"`
+	Stats
+	// Streak is the number of the last reports the member filled in a row, days out of office don't break it
+	Streak int `json:"streak"`
+}
+
+// TeamStats is the participation of a team and its members since a date
+type TeamStats struct {
+	Team    string        `json:"team"`
+	Since   time.Time     `json:"since"`
+	Members []MemberStats `json:"members"`
+	Stats
+}
+
+func (s *Stats) add(report *Report) {
+	s.Reported++
+	if report.Skipped {
+		s.Skipped++
+	}
+	if !report.SubmittedAt.IsZero() && !report.Window.ReportAt.IsZero() {
+		s.early += report.Window.ReportAt.Sub(report.SubmittedAt)
+		s.samples++
+	}
+}
+
+func (s *Stats) merge(other Stats) {
+	s.Expected += other.Expected
+	s.Reported += other.Reported
+	s.Skipped += other.Skipped
+	s.early += other.early
+	s.samples += other.samples
+}
+
+func (s *Stats) compute() {
+	if s.Expected > 0 {
+		s.CompletionRate = float64(s.Reported) / float64(s.Expected)
+	}
+	if s.Reported > 0 {
+		s.SkipRate = float64(s.Skipped) / float64(s.Reported)
+	}
+	if s.samples > 0 {
+		s.AverageBeforeDeadline = s.early / time.Duration(s.samples)
+		s.AverageSecondsEarly = s.AverageBeforeDeadline.Seconds()
+	}
+}
+
+// computeStats compiles the stored reports of a team. A report is expected from each
+// member for each report someone filled or was out of office for.
+func computeStats(team string, members []string, since time.Time, reports []*Report) *TeamStats {
+	windows := []string{}
+	seen := map[string]bool{}
+	byMember := map[string]map[string]*Report{}
+	for _, report := range reports {
+		window := windowKey(report) + "/" + report.questionSetKey()
+		if !seen[window] {
+			seen[window] = true
+			windows = append(windows, window)
+		}
+		if byMember[report.User] == nil {
+			byMember[report.User] = map[string]*Report{}
+		}
+		byMember[report.User][window] = report
+	}
+	// most recent first, for the streaks
+	sort.Sort(sort.Reverse(sort.StringSlice(windows)))
+
+	stats := &TeamStats{Team: team, Since: since, Members: []MemberStats{}}
+	for _, member := range members {
+		ms := MemberStats{User: member}
+		streaking := true
+		for _, window := range windows {
+			report, ok := byMember[member][window]
+			if ok && report.OutOfOffice {
+				continue
+			}
+			ms.Expected++
+			if !ok {
+				streaking = false
+				continue
+			}
+			ms.add(report)
+			if streaking {
+				ms.Streak++
+			}
+		}
+		ms.compute()
+		stats.merge(ms.Stats)
+		stats.Members = append(stats.Members, ms)
+	}
+	stats.compute()
+	return stats
+}
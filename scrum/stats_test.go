@@ -0,0 +1,37 @@
+package scrum
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsOfTheMembers(t *testing.T) {
+	monday := time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC)
+	report := func(user string, days int, early time.Duration) *Report {
+		date := monday.AddDate(0, 0, days)
+		reportAt := date.Add(9 * time.Hour)
+		return &Report{User: user, Date: date, Window: Window{Date: date, ReportAt: reportAt}, SubmittedAt: reportAt.Add(-early)}
+	}
+
+	skipped := report("U2", 1, time.Hour)
+	skipped.Skipped = true
+	outOfOffice := report("U2", 2, 0)
+	outOfOffice.Skipped, outOfOffice.OutOfOffice = true, true
+	reports := []*Report{
+		report("U1", 0, time.Hour), report("U1", 2, 30*time.Minute),
+		report("U2", 0, -time.Hour), skipped, outOfOffice,
+	}
+
+	stats := computeStats("L337", []string{"U1", "U2"}, monday, reports)
+
+	u1, u2 := stats.Members[0], stats.Members[1]
+	if u1.Expected != 3 || u1.Reported != 2 || u1.Streak != 1 || u1.AverageBeforeDeadline != 45*time.Minute {
+		t.Error("unexpected stats for U1", u1)
+	}
+	if u2.Expected != 2 || u2.Reported != 2 || u2.Skipped != 1 || u2.Streak != 2 || u2.SkipRate != 0.5 || u2.AverageBeforeDeadline != 0 {
+		t.Error("unexpected stats for U2", u2)
+	}
+	if stats.Expected != 5 || stats.Reported != 4 || stats.CompletionRate != 0.8 {
+		t.Error("unexpected team stats", stats.Stats)
+	}
+}
@@ -0,0 +1,273 @@
+package scrum
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReportStore keeps the reports once they are saved, a report redone replaces the previous one
+type ReportStore interface {
+	Save(report *Report) error
+	Delete(report *Report) error
+	// Reports lists the reports matching the filter, the most recent first
+	Reports(filter ReportFilter) ([]*Report, error)
+	// SaveBlocker keeps a blocker when it is raised and when it is resolved
+	SaveBlocker(blocker *Blocker) error
+	// Blockers lists the blockers kept, resolved or not
+	Blockers() ([]*Blocker, error)
+}
+
+// ReportFilter selects stored reports, the zero values match every report
+type ReportFilter struct {
+	Teams []string
+	User  string
+	// From and To are the first and last dates of the reports
+	From time.Time
+	To   time.Time
+	// Text is searched in the answers, ignoring the case
+	Text  string
+	Limit int
+}
+
+func (f ReportFilter) matches(report *Report) bool {
+	if len(f.Teams) > 0 && !containsFold(f.Teams, report.Team) {
+		return false
+	}
+	if f.User != "" && f.User != report.User {
+		return false
+	}
+	if !f.From.IsZero() && compareDates(report.Date, f.From) < 0 {
+		return false
+	}
+	if !f.To.IsZero() && compareDates(report.Date, f.To) > 0 {
+		return false
+	}
+	if f.Text != "" {
+		for _, answer := range report.Answers {
+			if strings.Contains(strings.ToLower(answer), strings.ToLower(f.Text)) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportKey identifies the report of a member for a window and set of questions
+func reportKey(report *Report) string {
+	return strings.Join([]string{report.Team, report.questionSetKey(), report.User, windowKey(report)}, "/")
+}
+
+// windowKey identifies the window of a report, the question sets reporting more than once
+// a day have several windows a day. The reports stored without a window only have a date.
+func windowKey(report *Report) string {
+	key := report.Date.Format(DateFormat)
+	if !report.Window.ReportAt.IsZero() {
+		key += "@" + report.Window.ReportAt.UTC().Format(time.RFC3339)
+	}
+	return key
+}
+
+// questionSetKey identifies a question set of a team in the stored reports, by its name or
+// by its rank among the unnamed sets. The first unnamed set keeps the empty name.
+func questionSetKey(name string, unnamedIndex int) string {
+	if name != "" || unnamedIndex == 0 {
+		return name
+	}
+	return "#" + strconv.Itoa(unnamedIndex)
+}
+
+func (r *Report) questionSetKey() string {
+	return questionSetKey(r.QuestionSet, r.QuestionSetIndex)
+}
+
+func (qs *QuestionSet) key() string {
+	return questionSetKey(qs.Name, qs.unnamedIndex)
+}
+
+// fileReportStore keeps the reports in memory and in a json file, when it has one.
+// The blockers are kept in a second json file next to it.
+type fileReportStore struct {
+	sync.Mutex
+	file     string
+	reports  map[string]*Report
+	blockers map[int]*Blocker
+}
+
+// NewFileReportStore loads the reports of a json file, the reports are only kept in memory without a file
+func NewFileReportStore(file string) (ReportStore, error) {
+	store := &fileReportStore{file: file, reports: map[string]*Report{}, blockers: map[int]*Blocker{}}
+	if file == "" {
+		return store, nil
+	}
+
+	reports := []*Report{}
+	if err := readJSON(file, &reports); err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		store.reports[reportKey(report)] = report
+	}
+
+	blockers := []*Blocker{}
+	if err := readJSON(store.blockersFile(), &blockers); err != nil {
+		return nil, err
+	}
+	for _, blocker := range blockers {
+		store.blockers[blocker.ID] = blocker
+	}
+	return store, nil
+}
+
+// blockersFile is the reports file with a .blockers.json extension, reports.blockers.json for reports.json
+func (s *fileReportStore) blockersFile() string {
+	return strings.TrimSuffix(s.file, filepath.Ext(s.file)) + ".blockers.json"
+}
+
+// readJSON decodes a json file, a missing file is left empty
+func readJSON(file string, v interface{}) error {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func (s *fileReportStore) Save(report *Report) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.reports[reportKey(report)] = report
+	return s.write()
+}
+
+func (s *fileReportStore) Delete(report *Report) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.reports[reportKey(report)] != report {
+		return nil
+	}
+	delete(s.reports, reportKey(report))
+	return s.write()
+}
+
+func (s *fileReportStore) Reports(filter ReportFilter) ([]*Report, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	reports := []*Report{}
+	for _, report := range s.reports {
+		if filter.matches(report) {
+			reports = append(reports, report)
+		}
+	}
+	sortReports(reports)
+	if filter.Limit > 0 && len(reports) > filter.Limit {
+		reports = reports[:filter.Limit]
+	}
+	return reports, nil
+}
+
+// sortReports sorts the reports by date and window, the most recent first, then by team and member
+func sortReports(reports []*Report) {
+	sort.Slice(reports, func(i, j int) bool {
+		if c := compareDates(reports[i].Date, reports[j].Date); c != 0 {
+			return c > 0
+		}
+		if !reports[i].Window.ReportAt.Equal(reports[j].Window.ReportAt) {
+			return reports[i].Window.ReportAt.After(reports[j].Window.ReportAt)
+		}
+		if reports[i].Team != reports[j].Team {
+			return reports[i].Team < reports[j].Team
+		}
+		return reports[i].User < reports[j].User
+	})
+}
+
+func (s *fileReportStore) SaveBlocker(blocker *Blocker) error {
+	s.Lock()
+	defer s.Unlock()
+
+	// a copy, the blocker is resolved later on
+	saved := *blocker
+	s.blockers[saved.ID] = &saved
+	if s.file == "" {
+		return nil
+	}
+
+	blockers := make([]*Blocker, 0, len(s.blockers))
+	for _, b := range s.blockers {
+		blockers = append(blockers, b)
+	}
+	sort.Slice(blockers, func(i, j int) bool { return blockers[i].ID < blockers[j].ID })
+	return writeJSON(s.blockersFile(), blockers)
+}
+
+func (s *fileReportStore) Blockers() ([]*Blocker, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	blockers := make([]*Blocker, 0, len(s.blockers))
+	for _, b := range s.blockers {
+		blocker := *b
+		blockers = append(blockers, &blocker)
+	}
+	sort.Slice(blockers, func(i, j int) bool { return blockers[i].ID < blockers[j].ID })
+	return blockers, nil
+}
+
+func (s *fileReportStore) write() error {
+	if s.file == "" {
+		return nil
+	}
+
+	reports := make([]*Report, 0, len(s.reports))
+	for _, report := range s.reports {
+		reports = append(reports, report)
+	}
+	sortReports(reports)
+	return writeJSON(s.file, reports)
+}
+
+// writeJSON replaces a file atomically so a crash never leaves it half written
+func writeJSON(file string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,94 @@
+package scrum
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileReportStoreKeepsTheReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrumpolice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "reports.json")
+
+	store, err := NewFileReportStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC)
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday, Answers: map[string]string{"today": "Working on the deploy"}})
+	store.Save(&Report{User: "U2", Team: "L337", Date: monday, Skipped: true, Answers: map[string]string{}})
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday.AddDate(0, 0, 1), Answers: map[string]string{"today": "Reviews"}})
+	// redone the same day
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday, Answers: map[string]string{"today": "Working on the DEPLOY pipeline"}})
+
+	reloaded, err := NewFileReportStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, _ := reloaded.Reports(ReportFilter{User: "U1"})
+	if len(reports) != 2 || compareDates(reports[0].Date, monday) != 1 {
+		t.Error("unexpected reports", reports)
+	}
+
+	reports, _ = reloaded.Reports(ReportFilter{Teams: []string{"l337"}, Text: "deploy"})
+	if len(reports) != 1 || reports[0].Answers["today"] != "Working on the DEPLOY pipeline" {
+		t.Error("unexpected search results", reports)
+	}
+
+	reports, _ = reloaded.Reports(ReportFilter{To: monday, Limit: 1})
+	if len(reports) != 1 || compareDates(reports[0].Date, monday) != 0 {
+		t.Error("unexpected filtered reports", reports)
+	}
+}
+
+func TestFileReportStoreKeepsTheReportsOfEachWindow(t *testing.T) {
+	store, _ := NewFileReportStore("")
+	monday := time.Date(2018, 3, 12, 0, 0, 0, 0, time.UTC)
+	morning := Window{Date: monday, ReportAt: monday.Add(9 * time.Hour)}
+	afternoon := Window{Date: monday, ReportAt: monday.Add(16 * time.Hour)}
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday, Window: morning, Answers: map[string]string{"today": "Reviews"}})
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday, Window: afternoon, Answers: map[string]string{"today": "Deploy"}})
+	// redone in the same window
+	store.Save(&Report{User: "U1", Team: "L337", Date: monday, Window: morning, Answers: map[string]string{"today": "Code reviews"}})
+
+	reports, _ := store.Reports(ReportFilter{User: "U1"})
+	if len(reports) != 2 || reports[0].Answers["today"] != "Deploy" || reports[1].Answers["today"] != "Code reviews" {
+		t.Error("unexpected reports", reports)
+	}
+}
+
+func TestBlockersSurviveARestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scrumpolice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "reports.json")
+
+	store, _ := NewFileReportStore(file)
+	registry := newBlockerRegistry(store)
+	raisedAt := time.Date(2018, 3, 5, 9, 0, 0, 0, time.UTC)
+	resolved, _ := registry.raise("L337", "pa", "blockers", "Waiting on a review", raisedAt)
+	registry.raise("L337", "jo", "blockers", "No access to prod", raisedAt)
+	registry.resolve(resolved.ID, "pa", raisedAt.Add(time.Hour))
+
+	store, err = NewFileReportStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry = newBlockerRegistry(store)
+	open := registry.open("L337")
+	if len(open) != 1 || open[0].User != "jo" || open[0].Text != "No access to prod" {
+		t.Error("unexpected open blockers", open)
+	}
+	if blocker, isNew := registry.raise("L337", "pa", "blockers", "Waiting on a deploy", raisedAt); !isNew || blocker.ID != 3 {
+		t.Error("the blocker ids are reused", blocker)
+	}
+}
@@ -0,0 +1,220 @@
+package scrum
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Events sent to the webhooks
+const (
+	ReportSavedEvent   = "report_saved"
+	ReportSkippedEvent = "report_skipped"
+	ReminderSentEvent  = "reminder_sent"
+	ReportPostedEvent  = "report_posted"
+)
+
+// WebhookEvents are the events a webhook can receive
+var WebhookEvents = []string{ReportSavedEvent, ReportSkippedEvent, ReminderSentEvent, ReportPostedEvent}
+
+const (
+	// WebhookEventHeader is the event of a webhook payload
+	WebhookEventHeader = "X-Scrumpolice-Event"
+	// WebhookSignatureHeader is the hex HMAC-SHA256 of a webhook payload with the webhook secret, prefixed by "sha256="
+	WebhookSignatureHeader = "X-Scrumpolice-Signature"
+)
+
+// webhookPayload is the json sent to the webhooks, with the report, reports or reminder of its event
+type webhookPayload struct {
+	Event       string           `json:"event"`
+	Team        string           `json:"team"`
+	QuestionSet string           `json:"question_set,omitempty"`
+	Date        string           `json:"date,omitempty"`
+	SentAt      time.Time        `json:"sent_at"`
+	Report      *exportedReport  `json:"report,omitempty"`
+	Reports     []exportedReport `json:"reports,omitempty"`
+	Reminder    *webhookReminder `json:"reminder,omitempty"`
+}
+
+type webhookReminder struct {
+	Audience Audience `json:"audience"`
+	// Missing are the members who did not report yet
+	Missing []string `json:"missing"`
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Webhook) receives(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signPayload is the value of the WebhookSignatureHeader of a payload
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookClient posts the payloads to the webhooks, the failed attempts are
+// retried after a backoff doubling each time
+type webhookClient struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+func newWebhookClient() *webhookClient {
+	return &webhookClient{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: 5,
+		backoff:  time.Second,
+	}
+}
+
+func (c *webhookClient) post(webhook Webhook, event string, payload []byte) error {
+	var err error
+	for attempt := 0; attempt < c.attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.backoff << uint(attempt-1))
+		}
+
+		var retry bool
+		retry, err = c.postOnce(webhook, event, payload)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// postOnce posts a payload, and tells if it is worth retrying when it fails
+func (c *webhookClient) postOnce(webhook Webhook, event string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, signPayload(webhook.Secret, payload))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	// the other client errors won't get better
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("webhook answered %s", resp.Status)
+}
+
+// sendWebhooks posts an event to the global webhooks and to the ones of the team
+// in the background, the payload is only built when some webhook receives the event
+func (ts *TeamState) sendWebhooks(event string, payload func() webhookPayload) {
+	client := ts.service.webhookClient
+	if client == nil {
+		return
+	}
+
+	webhooks := []Webhook{}
+	for _, webhook := range append(append([]Webhook{}, ts.service.webhooks...), ts.Team.Webhooks...) {
+		if webhook.receives(event) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	p := payload()
+	p.Event = event
+	p.Team = ts.Team.Name
+	p.SentAt = time.Now()
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"team":  ts.Team.Name,
+			"event": event,
+			"error": err,
+		}).Error("Cannot encode webhook payload.")
+		return
+	}
+
+	for _, webhook := range webhooks {
+		go func(webhook Webhook) {
+			if err := client.post(webhook, event, body); err != nil {
+				log.WithFields(log.Fields{
+					"team":  ts.Team.Name,
+					"event": event,
+					"error": err,
+				}).Warn("Cannot send webhook, giving up.")
+			}
+		}(webhook)
+	}
+}
+
+// reportWebhook sends the report saved or skipped event of a report
+func (ts *TeamState) reportWebhook(report *Report) {
+	event := ReportSavedEvent
+	if report.Skipped {
+		event = ReportSkippedEvent
+	}
+	ts.sendWebhooks(event, func() webhookPayload {
+		exported := exportReport(report, ts.service.users.UserName)
+		return webhookPayload{QuestionSet: report.QuestionSet, Date: exported.Date, Report: &exported}
+	})
+}
+
+// postedWebhook sends the report posted event of the report of a question set
+func (ts *TeamState) postedWebhook(qsstate *questionSetState) {
+	ts.sendWebhooks(ReportPostedEvent, func() webhookPayload {
+		reports := []exportedReport{}
+		for _, report := range qsstate.enteredReports {
+			reports = append(reports, exportReport(report, ts.service.users.UserName))
+		}
+		sort.Slice(reports, func(i, j int) bool { return reports[i].User < reports[j].User })
+		return webhookPayload{QuestionSet: qsstate.Name, Date: qsstate.window.Date.Format(DateFormat), Reports: reports}
+	})
+}
+
+// reminderWebhook sends the reminder sent event of a reminder to the missing members
+func (ts *TeamState) reminderWebhook(qs *QuestionSet, reminder Reminder, missing []string) {
+	ts.sendWebhooks(ReminderSentEvent, func() webhookPayload {
+		names := make([]string, len(missing))
+		for i, member := range missing {
+			names[i] = ts.service.users.UserName(member)
+		}
+		return webhookPayload{QuestionSet: qs.Name, Reminder: &webhookReminder{Audience: reminder.Audience, Missing: names}}
+	})
+}
@@ -0,0 +1,104 @@
+package scrum
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// namesDirectory names the users by their id
+type namesDirectory struct{}
+
+func (namesDirectory) UserID(user string) (string, bool)     { return user, true }
+func (namesDirectory) UserName(id string) string             { return "name-" + id }
+func (namesDirectory) UserLocation(id string) *time.Location { return nil }
+
+func TestWebhookIsRetriedUntilItSucceeds(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(WebhookSignatureHeader) != signPayload("s3cr3t", body) || r.Header.Get(WebhookEventHeader) != ReportSavedEvent {
+			t.Error("unexpected headers", r.Header)
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := &webhookClient{client: server.Client(), attempts: 5, backoff: time.Millisecond}
+	if err := client.post(Webhook{URL: server.URL, Secret: "s3cr3t"}, ReportSavedEvent, []byte(`{}`)); err != nil || attempts != 3 {
+		t.Error("the webhook is not retried", attempts, err)
+	}
+}
+
+func TestWebhookClientErrorsAreNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get(WebhookSignatureHeader) != "" {
+			t.Error("a webhook without secret is not signed")
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := &webhookClient{client: server.Client(), attempts: 5, backoff: time.Millisecond}
+	if err := client.post(Webhook{URL: server.URL}, ReportSavedEvent, []byte(`{}`)); err == nil || attempts != 1 {
+		t.Error("a bad request is not retried", attempts, err)
+	}
+}
+
+func TestWebhooksReceiveTheirEvents(t *testing.T) {
+	var lock sync.Mutex
+	received := map[string]webhookPayload{}
+	done := make(chan bool, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := webhookPayload{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		lock.Lock()
+		received[r.URL.Path+" "+payload.Event] = payload
+		lock.Unlock()
+		done <- true
+	}))
+	defer server.Close()
+
+	m := &service{
+		users:         namesDirectory{},
+		webhooks:      []Webhook{{URL: server.URL + "/global"}},
+		webhookClient: &webhookClient{client: server.Client(), attempts: 1},
+	}
+	ts := &TeamState{
+		Team:    &Team{Name: "L337", Webhooks: []Webhook{{URL: server.URL + "/team", Events: []string{ReportSkippedEvent}}}},
+		service: m,
+	}
+
+	date := time.Date(2018, 3, 9, 0, 0, 0, 0, time.UTC)
+	ts.reportWebhook(&Report{User: "U1", Team: "L337", Date: date, Answers: map[string]string{"today": "Ship it"}})
+	ts.reportWebhook(&Report{User: "U2", Team: "L337", Date: date, Skipped: true, Answers: map[string]string{}})
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the webhooks were not sent", received)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	saved, ok := received["/global "+ReportSavedEvent]
+	if !ok || saved.Team != "L337" || saved.Date != "2018-03-09" || saved.Report.User != "name-U1" || saved.Report.Answers["today"] != "Ship it" {
+		t.Error("unexpected saved payload", saved)
+	}
+	if _, ok := received["/team "+ReportSkippedEvent]; !ok {
+		t.Error("the team webhook did not receive the skipped report")
+	}
+	if _, ok := received["/team "+ReportSavedEvent]; ok {
+		t.Error("the team webhook only receives the skipped reports")
+	}
+}
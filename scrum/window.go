@@ -0,0 +1,140 @@
+package scrum
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateFormat is the format of the report dates shown to and typed by the users
+const DateFormat = "2006-01-02"
+
+// windowTimeFormat is the format of the window times shown to the users
+const windowTimeFormat = "2006-01-02 15:04 MST"
+
+// Window is the period during which the reports of a date are entered
+type Window struct {
+	// Date of the report
+	Date time.Time
+	// OpensAt is when the reports start to be accepted
+	OpensAt time.Time
+	// ReportAt is when the report is posted
+	ReportAt time.Time
+	// ClosesAt is when the late reports stop being added to the posted report
+	ClosesAt time.Time
+}
+
+// isOpen tells if reports can be entered in the window now
+func (w Window) isOpen(now time.Time) bool {
+	return !now.Before(w.OpensAt) && !now.After(w.ClosesAt)
+}
+
+// same tells if two windows are the window of the same report
+func (w Window) same(other Window) bool {
+	return w.ReportAt.Equal(other.ReportAt)
+}
+
+// check tells why a report can't be entered in the window now, if it can't
+func (w Window) check(now time.Time) error {
+	if now.Before(w.OpensAt) {
+		return fmt.Errorf("The report of %s opens at %s, come back then", w.Date.Format(DateFormat), w.OpensAt.Format(windowTimeFormat))
+	}
+	if now.After(w.ClosesAt) {
+		return fmt.Errorf("The report of %s closed at %s, it's too late to fill it", w.Date.Format(DateFormat), w.ClosesAt.Format(windowTimeFormat))
+	}
+	return nil
+}
+
+// window is the window of the next report of the question set, the reports
+// entered now go in it. Without a configured report window, it opens now (after
+// the previous report) and closes at the end of the late grace period.
+func (ts *TeamState) window(qs *QuestionSet, now time.Time) Window {
+	now = now.In(ts.location)
+	next := qs.ReportSchedule.Next(now)
+	w := ts.windowAt(qs, next)
+	if qs.ReportWindow == nil {
+		w.OpensAt = now
+	}
+	return w
+}
+
+// dateWindow is the window of the report of the question set scheduled on a date
+func (ts *TeamState) dateWindow(qs *QuestionSet, date time.Time) Window {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, ts.location)
+	w := ts.windowAt(qs, qs.ReportSchedule.Next(day.Add(-time.Second)))
+	w.Date = day
+	return w
+}
+
+func (ts *TeamState) windowAt(qs *QuestionSet, reportAt time.Time) Window {
+	// without a configured report window, it's open since about the previous daily report
+	w := Window{
+		Date:     time.Date(reportAt.Year(), reportAt.Month(), reportAt.Day(), 0, 0, 0, 0, ts.location),
+		OpensAt:  reportAt.AddDate(0, 0, -1),
+		ReportAt: reportAt,
+		ClosesAt: reportAt.Add(qs.LateGracePeriod),
+	}
+	if qs.ReportWindow != nil {
+		// late reports are still added to the posted report during the grace period after it closes
+		w.OpensAt = reportAt.Add(qs.ReportWindow.Opens)
+		w.ClosesAt = reportAt.Add(qs.ReportWindow.Closes + qs.LateGracePeriod)
+	}
+	return w
+}
+
+// compareDates compares the calendar dates of two times, each in its own location
+func compareDates(a, b time.Time) int {
+	da, db := a.Format(DateFormat), b.Format(DateFormat)
+	switch {
+	case da < db:
+		return -1
+	case da > db:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// reportWindow is the window of the report of a date, or the one reports entered
+// now go in when the date is not set
+func (ts *TeamState) reportWindow(qsstate *questionSetState, date time.Time, now time.Time) Window {
+	if date.IsZero() {
+		// shortly after a report is posted, reports are late for it rather than early for the next one
+		if last := qsstate.previousPosted; last != nil && now.Before(last.window.ClosesAt) {
+			return last.window
+		}
+		return qsstate.window
+	}
+
+	// a question set reporting more than once a day has several windows on the date, like
+	// without a date the report is late for a posted report still open, else for the last one
+	windows := []Window{}
+	for _, posted := range []*postedReport{qsstate.previousPosted, qsstate.posted} {
+		if posted != nil && compareDates(posted.window.Date, date) == 0 {
+			windows = append(windows, posted.window)
+		}
+	}
+	if compareDates(qsstate.window.Date, date) == 0 {
+		windows = append(windows, qsstate.window)
+	}
+	if len(windows) == 0 {
+		return ts.dateWindow(qsstate.QuestionSet, date)
+	}
+	for _, window := range windows {
+		if window.isOpen(now) {
+			return window
+		}
+	}
+	return windows[len(windows)-1]
+}
+
+// openWindow is the report window of a date when reports can be entered in it now.
+// Without a configured report window, the reports entered before it opens wait for
+// it and the ones entered after it closes are late addendums. The reports entered
+// for a future date always wait for their window.
+func (ts *TeamState) openWindow(qsstate *questionSetState, date time.Time, now time.Time) (Window, error) {
+	window := ts.reportWindow(qsstate, date, now)
+	if qsstate.ReportWindow == nil || (!date.IsZero() && now.Before(window.OpensAt)) {
+		return window, nil
+	}
+	return window, window.check(now)
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 
 	"github.com/nlopes/slack"
@@ -22,6 +23,13 @@ const header = "                                           _ _\n" +
 const Version = "0.7.1"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := export(os.Args[2:]); err != nil {
+			log.Fatalln("export failed:", err)
+		}
+		return
+	}
+
 	fmt.Println(header)
 	fmt.Println("Version", Version)
 	fmt.Println("")
@@ -34,15 +42,35 @@ func main() {
 
 	configFile := "config.json"
 	flag.StringVar(&configFile, "config", configFile, "The configuration file")
+	reportsFile := "reports.json"
+	flag.StringVar(&reportsFile, "reports", reportsFile, "The file the reports are stored in, they are only kept in memory when empty")
+	adminAddr := ""
+	flag.StringVar(&adminAddr, "admin", adminAddr, "The address of the admin API (e.g. :8080), disabled when empty")
 	flag.Parse()
 
 	// Injection
 	logger := logrus.New()
 	configurationProvider := scrum.NewConfigWatcher(configFile)
 	slackAPIClient := slack.New(slackBotToken)
-	scrum := scrum.NewService(configurationProvider, slackAPIClient)
+	users := bot.NewUserDirectory(slackAPIClient)
+	store, err := scrum.NewFileReportStore(reportsFile)
+	if err != nil {
+		log.Fatalln("cannot load the reports file:", err)
+	}
+	scrumService := scrum.NewService(configurationProvider, slackAPIClient, users, store)
+
+	if adminAddr != "" {
+		adminToken := os.Getenv("SCRUMPOLICE_ADMIN_TOKEN")
+		if adminToken == "" {
+			log.Fatalln("admin token must be set in SCRUMPOLICE_ADMIN_TOKEN to serve the admin API")
+		}
+		api := &adminAPI{token: adminToken, scrum: scrumService, logger: logger}
+		go func() {
+			log.Fatalln(http.ListenAndServe(adminAddr, api.handler()))
+		}()
+	}
 
 	// Create and run bot
-	b := bot.New(slackAPIClient, logger, scrum)
+	b := bot.New(slackAPIClient, logger, scrumService, users)
 	b.Run()
 }